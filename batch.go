@@ -0,0 +1,203 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// recordDeadlineMargin is how long before the invocation deadline the record handler middlewares stop processing
+// records, so the BatchResponse reaches Lambda before the function is killed
+const recordDeadlineMargin = 500 * time.Millisecond
+
+// BatchResponse is the response returned to Lambda to report the records of a batch that failed processing.
+// The event source mapping must have ReportBatchItemFailures enabled for only the failed records to be retried.
+type BatchResponse struct {
+	BatchItemFailures []BatchItemFailure `json:"batchItemFailures"`
+}
+
+// BatchItemFailure identifies a single record that failed processing.
+// For SQS this is the message ID, and for Kinesis and DynamoDB streams the sequence number.
+type BatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// batchRecord is a single record of a batched event, independent of the event source
+type batchRecord struct {
+	// id is reported in the BatchResponse if the record fails
+	id string
+	// group is the ordering key of the record. Records sharing a non-empty group are processed sequentially,
+	// in the order they appear in the batch
+	group string
+	// withContext stores the original event record in the context given to the handler
+	withContext func(context.Context) context.Context
+	// decode converts the record into the handler input parameter type
+	decode func(tIn reflect.Type) (interface{}, error)
+}
+
+// recordHandlerMiddleware calls the handler once per record returned by parse, processing at most concurrency
+// records at the same time, and reports the records which failed in a BatchResponse.
+// Records are processed until recordDeadlineMargin before the invocation deadline, after which the records which
+// have not been processed are reported as failures.
+func recordHandlerMiddleware(name string, concurrency int, validateTIn func(reflect.Type) error, parse func(interface{}) ([]batchRecord, error)) func(LambdaFunc) LambdaFunc {
+	return NamedMiddleware(name, func(next LambdaFunc) LambdaFunc {
+		handleRecord := func(ctx context.Context, tIn reflect.Type, r batchRecord) error {
+			ctx = r.withContext(ctx)
+			if tIn == nil {
+				_, err := next(ctx, nil)
+				return err
+			}
+			in, err := r.decode(tIn)
+			if err != nil {
+				return err
+			}
+			_, err = next(ctx, in)
+			return err
+		}
+
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			tIn, _ := TInFromContext(ctx)
+			if tIn != nil {
+				if err := validateTIn(tIn); err != nil {
+					return nil, err
+				}
+			}
+			records, err := parse(in)
+			if err != nil {
+				return nil, err
+			}
+			if deadline, ok := ctx.Deadline(); ok {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, deadline.Add(-clampMargin(recordDeadlineMargin, time.Until(deadline))))
+				defer cancel()
+			}
+			failures := processBatch(ctx, records, concurrency, func(ctx context.Context, r batchRecord) error {
+				return handleRecord(ctx, tIn, r)
+			})
+
+			rsp := BatchResponse{BatchItemFailures: []BatchItemFailure{}}
+			for i, err := range failures {
+				if err != nil {
+					middlewareLogger(ctx, name).Warn("failed to process record", "recordId", records[i].id, "error", err)
+					rsp.BatchItemFailures = append(rsp.BatchItemFailures, BatchItemFailure{ItemIdentifier: records[i].id})
+				}
+			}
+			return rsp, nil
+		}
+	})
+}
+
+var errBatchDeadline = errors.New("record was not processed before the invocation deadline")
+
+// clampMargin limits a safety margin before a deadline to half of the time remaining, so the rest of the time is
+// still given to the handler
+func clampMargin(margin, remaining time.Duration) time.Duration {
+	if limit := remaining / 2; margin > limit {
+		return limit
+	}
+	return margin
+}
+
+// processBatch calls handle for every record using a pool of concurrency workers, and returns the error
+// for each record by index.
+// Records sharing a group are processed in order by a single worker, and once one of them fails the
+// remaining records in the group are failed without being processed so ordering is preserved on retry.
+// Once the context is done no further records are processed, and processBatch returns without waiting for
+// records which are still being handled, failing them so the batch response can be returned before the timeout.
+// A panic while handling a record is converted into a *PanicError for the record, as it cannot be recovered
+// from outside the worker.
+func processBatch(ctx context.Context, records []batchRecord, concurrency int, handle func(context.Context, batchRecord) error) []error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	failures := make([]error, len(records))
+	finished := make([]bool, len(records))
+	var mu sync.Mutex
+	returned := false
+	setFailure := func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !returned {
+			failures[i] = err
+			finished[i] = true
+		}
+	}
+	groups := groupRecords(records)
+
+	work := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(groups); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range work {
+				var groupErr error
+				for _, i := range group {
+					switch {
+					case groupErr != nil:
+						setFailure(i, fmt.Errorf("skipped as a previous record in group %s failed: %w", records[i].group, groupErr))
+					case ctx.Err() != nil:
+						setFailure(i, errBatchDeadline)
+					default:
+						err := recoverError(func() error {
+							return handle(ctx, records[i])
+						})
+						setFailure(i, err)
+						if records[i].group != "" {
+							groupErr = err
+						}
+					}
+				}
+			}
+		}()
+	}
+	allDone := make(chan struct{})
+	go func() {
+		defer close(allDone)
+		defer wg.Wait()
+		defer close(work)
+		for _, group := range groups {
+			select {
+			case work <- group:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	select {
+	case <-allDone:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	returned = true
+	for i := range failures {
+		if !finished[i] {
+			failures[i] = errBatchDeadline
+		}
+	}
+	return failures
+}
+
+// groupRecords splits the records into groups of indexes, in order of first appearance
+func groupRecords(records []batchRecord) [][]int {
+	var groups [][]int
+	groupIndex := map[string]int{}
+	for i, r := range records {
+		if r.group == "" {
+			groups = append(groups, []int{i})
+			continue
+		}
+		if g, ok := groupIndex[r.group]; ok {
+			groups[g] = append(groups[g], i)
+			continue
+		}
+		groupIndex[r.group] = len(groups)
+		groups = append(groups, []int{i})
+	}
+	return groups
+}
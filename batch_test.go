@@ -0,0 +1,159 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBatchRecord(id string, group string) batchRecord {
+	return batchRecord{
+		id:    id,
+		group: group,
+		withContext: func(ctx context.Context) context.Context {
+			return ctx
+		},
+		decode: func(reflect.Type) (interface{}, error) {
+			return id, nil
+		},
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	t.Run("bounded concurrency", func(t *testing.T) {
+		var records []batchRecord
+		for i := 0; i < 20; i++ {
+			records = append(records, newTestBatchRecord(fmt.Sprint(i), ""))
+		}
+		var running, maxRunning int32
+		failures := processBatch(context.Background(), records, 4, func(ctx context.Context, r batchRecord) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+		assert.Equal(t, make([]error, 20), failures)
+		assert.LessOrEqual(t, maxRunning, int32(4))
+		assert.Greater(t, maxRunning, int32(1))
+	})
+
+	t.Run("records in a group are processed in order", func(t *testing.T) {
+		records := []batchRecord{
+			newTestBatchRecord("a1", "a"),
+			newTestBatchRecord("b1", "b"),
+			newTestBatchRecord("a2", "a"),
+			newTestBatchRecord("b2", "b"),
+			newTestBatchRecord("a3", "a"),
+		}
+		var mu sync.Mutex
+		var processed []string
+		processBatch(context.Background(), records, 2, func(ctx context.Context, r batchRecord) error {
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, r.id)
+			return nil
+		})
+		var groupA []string
+		for _, id := range processed {
+			if id[0] == 'a' {
+				groupA = append(groupA, id)
+			}
+		}
+		assert.Equal(t, []string{"a1", "a2", "a3"}, groupA)
+		assert.Len(t, processed, 5)
+	})
+
+	t.Run("remaining records in a group are skipped after a failure", func(t *testing.T) {
+		records := []batchRecord{
+			newTestBatchRecord("a1", "a"),
+			newTestBatchRecord("a2", "a"),
+			newTestBatchRecord("b1", "b"),
+			newTestBatchRecord("a3", "a"),
+		}
+		var mu sync.Mutex
+		var processed []string
+		failures := processBatch(context.Background(), records, 2, func(ctx context.Context, r batchRecord) error {
+			mu.Lock()
+			processed = append(processed, r.id)
+			mu.Unlock()
+			if r.id == "a2" {
+				return errors.New("something happened")
+			}
+			return nil
+		})
+		assert.NoError(t, failures[0])
+		assert.Error(t, failures[1])
+		assert.NoError(t, failures[2])
+		assert.Error(t, failures[3])
+		assert.NotContains(t, processed, "a3")
+	})
+
+	t.Run("records are not processed once the context is done", func(t *testing.T) {
+		records := []batchRecord{
+			newTestBatchRecord("1", ""),
+			newTestBatchRecord("2", ""),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		failures := processBatch(ctx, records, 1, func(ctx context.Context, r batchRecord) error {
+			t.Error("unexpected call to handle")
+			return nil
+		})
+		assert.Equal(t, []error{errBatchDeadline, errBatchDeadline}, failures)
+	})
+
+	t.Run("panics are converted into record failures", func(t *testing.T) {
+		records := []batchRecord{
+			newTestBatchRecord("1", ""),
+			newTestBatchRecord("2", ""),
+		}
+		failures := processBatch(context.Background(), records, 2, func(ctx context.Context, r batchRecord) error {
+			if r.id == "2" {
+				panic("boom")
+			}
+			return nil
+		})
+		assert.NoError(t, failures[0])
+		var panicErr *PanicError
+		assert.True(t, errors.As(failures[1], &panicErr))
+		assert.Equal(t, "boom", panicErr.Value)
+	})
+}
+
+func TestRecordHandlerMiddlewareDeadline(t *testing.T) {
+	parse := func(interface{}) ([]batchRecord, error) {
+		return []batchRecord{newTestBatchRecord("1", ""), newTestBatchRecord("2", ""), newTestBatchRecord("3", "")}, nil
+	}
+	m := recordHandlerMiddleware("TestRecordHandlerMiddleware", 1, func(reflect.Type) error { return nil }, parse)
+	// record 2 ignores its context and overruns the deadline
+	block := make(chan struct{})
+	defer close(block)
+	h := m(func(ctx context.Context, in interface{}) (interface{}, error) {
+		if in == "2" {
+			<-block
+		}
+		return nil, nil
+	})
+
+	deadline := time.Now().Add(200 * time.Millisecond)
+	ctx, cancel := context.WithDeadline(context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf("")), deadline)
+	defer cancel()
+	rsp, err := h(ctx, nil)
+	assert.NoError(t, err)
+	// the records are failed a margin before the deadline, so the response is returned before the function is killed
+	assert.True(t, time.Now().Before(deadline))
+	assert.Equal(t, BatchResponse{BatchItemFailures: []BatchItemFailure{{ItemIdentifier: "2"}, {ItemIdentifier: "3"}}}, rsp)
+}
@@ -0,0 +1,30 @@
+// Command vesper-local serves the Lambda Invoke API and Runtime API on localhost, for a function running in another
+// process to take invocations from. The function is started with AWS_LAMBDA_RUNTIME_API set to the address, which
+// lambda.Start of aws-lambda-go v1.18.0 or later, and so Vesper.Start, polls for invocations:
+//
+//	vesper-local -addr localhost:9001 -timeout 10s &
+//	AWS_LAMBDA_RUNTIME_API=localhost:9001 go run ./myfunction &
+//	aws lambda invoke --endpoint-url http://localhost:9001 --function-name users --payload '{}' out.json
+//
+// To run a function in the same process, use local.ListenAndServe instead.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/mefellows/vesper/local"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9001", "address to serve the Invoke API and Runtime API on")
+	timeout := flag.Duration("timeout", local.DefaultTimeout, "timeout of invocations")
+	region := flag.String("region", local.DefaultRegion, "region of the ARNs functions are invoked with")
+	accountID := flag.String("account-id", local.DefaultAccountID, "account ID of the ARNs functions are invoked with")
+	flag.Parse()
+
+	s := local.NewServer(local.WithTimeout(*timeout), local.WithRegion(*region), local.WithAccountID(*accountID))
+	log.Printf("serving the Lambda Invoke API and Runtime API on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
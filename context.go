@@ -3,6 +3,8 @@ package vesper
 import (
 	"context"
 	"reflect"
+
+	"github.com/aws/aws-lambda-go/events"
 )
 
 type ctxKey string
@@ -10,6 +12,19 @@ type ctxKey string
 const (
 	ctxKeyPayload = ctxKey("payload")
 	ctxKeyTIn     = ctxKey("TIn")
+	ctxKeyLogger  = ctxKey("Logger")
+
+	ctxKeyColdStart              = ctxKey("ColdStart")
+	ctxKeyCorrelationID          = ctxKey("CorrelationID")
+	ctxKeyInstrumentedMiddleware = ctxKey("InstrumentedMiddleware")
+
+	ctxKeySQSMessage     = ctxKey("SQSMessage")
+	ctxKeyKinesisRecord  = ctxKey("KinesisRecord")
+	ctxKeyDynamoDBRecord = ctxKey("DynamoDBRecord")
+	ctxKeyHTTPRequest    = ctxKey("HTTPRequest")
+	ctxKeyEventSource    = ctxKey("EventSource")
+
+	ctxKeyEventBridgeEvent = ctxKey("EventBridgeEvent")
 )
 
 // PayloadFromContext retrieves the original payload with type []byte from a context.
@@ -23,3 +38,39 @@ func TInFromContext(ctx context.Context) (reflect.Type, bool) {
 	value, ok := ctx.Value(ctxKeyTIn).(reflect.Type)
 	return value, ok
 }
+
+// SQSMessageFromContext retrieves the SQS message being processed by a record handler from a context.
+func SQSMessageFromContext(ctx context.Context) (events.SQSMessage, bool) {
+	value, ok := ctx.Value(ctxKeySQSMessage).(events.SQSMessage)
+	return value, ok
+}
+
+// KinesisRecordFromContext retrieves the Kinesis record being processed by a record handler from a context.
+func KinesisRecordFromContext(ctx context.Context) (events.KinesisEventRecord, bool) {
+	value, ok := ctx.Value(ctxKeyKinesisRecord).(events.KinesisEventRecord)
+	return value, ok
+}
+
+// DynamoDBRecordFromContext retrieves the DynamoDB stream record being processed by a record handler from a context.
+func DynamoDBRecordFromContext(ctx context.Context) (events.DynamoDBEventRecord, bool) {
+	value, ok := ctx.Value(ctxKeyDynamoDBRecord).(events.DynamoDBEventRecord)
+	return value, ok
+}
+
+// HTTPRequestFromContext retrieves the HTTPRequest being handled by a Router route from a context.
+func HTTPRequestFromContext(ctx context.Context) (HTTPRequest, bool) {
+	value, ok := ctx.Value(ctxKeyHTTPRequest).(HTTPRequest)
+	return value, ok
+}
+
+// EventSourceFromContext retrieves the source of the event being handled by an EventRouter route from a context.
+func EventSourceFromContext(ctx context.Context) (EventSource, bool) {
+	value, ok := ctx.Value(ctxKeyEventSource).(EventSource)
+	return value, ok
+}
+
+// EventBridgeEventFromContext retrieves the events.CloudWatchEvent being handled by an EventBridgeRouter route from a context.
+func EventBridgeEventFromContext(ctx context.Context) (events.CloudWatchEvent, bool) {
+	value, ok := ctx.Value(ctxKeyEventBridgeEvent).(events.CloudWatchEvent)
+	return value, ok
+}
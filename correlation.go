@@ -0,0 +1,240 @@
+package vesper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CorrelationIDHeader is the HTTP header a correlation ID is read from and returned in
+const CorrelationIDHeader = "X-Correlation-Id"
+
+// CorrelationIDFromContext retrieves the correlation ID of the invocation, or of the record being processed by a
+// record handler, from a context.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	value, ok := ctx.Value(ctxKeyCorrelationID).(string)
+	return value, ok
+}
+
+// CorrelationIDMiddleware is a middleware which extracts a correlation ID from the event, generating one if there
+// is none, so that a request can be followed across services.
+// The ID is stored in the context, see CorrelationIDFromContext, and added to the fields of the invocation logger
+// as "correlationId". It is extracted from:
+//
+//   - HTTP events: the X-Correlation-Id header, falling back to the root of the X-Amzn-Trace-Id header
+//   - SQS messages: a correlationId message attribute, or one of the SNS envelope in the body
+//   - SNS messages: a correlationId message attribute
+//   - EventBridge events: a correlationId field of the event detail
+//
+// Attribute and field names are matched ignoring case and separators, so correlation-id and CorrelationId
+// also match, and correlationId is preferred to X-Correlation-Id when both are present.
+// When used after a record handler middleware the ID is extracted for each record, otherwise the ID of the first
+// record carrying one is used for the whole batch.
+// HTTPResponse and API Gateway or ALB response types returned for HTTP events have the ID added in the
+// X-Correlation-Id header.
+func CorrelationIDMiddleware() func(LambdaFunc) LambdaFunc {
+	return NamedMiddleware("CorrelationIDMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			id, source := extractCorrelationID(ctx)
+			if id == "" {
+				id, source = newCorrelationID(), "generated"
+			}
+			logger := LoggerFromContext(ctx).With("correlationId", id)
+			logger.With("middleware", "CorrelationIDMiddleware").Debug("correlation ID for event", "source", source)
+			ctx = context.WithValue(ctx, ctxKeyCorrelationID, id)
+			ctx = context.WithValue(ctx, ctxKeyLogger, logger)
+
+			res, err := next(ctx, in)
+			if err != nil {
+				return res, err
+			}
+			if _, ok := httpRequestFromPayload(ctx); ok {
+				res = withResponseHeader(res, CorrelationIDHeader, id)
+			}
+			return res, nil
+		}
+	})
+}
+
+// correlationEvent is the subset of the supported events which may carry a correlation ID
+type correlationEvent struct {
+	Records []struct {
+		MessageAttributes map[string]events.SQSMessageAttribute `json:"messageAttributes"`
+		Body              string                                `json:"body"`
+		SNS               struct {
+			MessageAttributes map[string]snsMessageAttribute `json:"MessageAttributes"`
+		} `json:"Sns"`
+	} `json:"Records"`
+	DetailType string          `json:"detail-type"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// snsMessageAttribute is a message attribute of an SNS message, or of an SNS envelope delivered via SQS
+type snsMessageAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// extractCorrelationID returns the correlation ID of the event, and where it was found
+func extractCorrelationID(ctx context.Context) (string, string) {
+	if msg, ok := SQSMessageFromContext(ctx); ok {
+		return sqsCorrelationID(msg.MessageAttributes, msg.Body), "sqs"
+	}
+	if req, ok := httpRequestFromPayload(ctx); ok {
+		if id := req.Header(CorrelationIDHeader); id != "" {
+			return id, "http"
+		}
+		if id := traceRoot(req.Header("X-Amzn-Trace-Id")); id != "" {
+			return id, "http"
+		}
+		return "", ""
+	}
+
+	payload, ok := PayloadFromContext(ctx)
+	if !ok {
+		return "", ""
+	}
+	var event correlationEvent
+	if json.Unmarshal(payload, &event) != nil {
+		return "", ""
+	}
+	for _, r := range event.Records {
+		if id := sqsCorrelationID(r.MessageAttributes, r.Body); id != "" {
+			return id, "sqs"
+		}
+		if id := snsCorrelationID(r.SNS.MessageAttributes); id != "" {
+			return id, "sns"
+		}
+	}
+	if event.DetailType != "" {
+		var detail map[string]interface{}
+		if json.Unmarshal(event.Detail, &detail) == nil {
+			id := lookupCorrelationID(detail, func(v interface{}) string {
+				s, _ := v.(string)
+				return s
+			})
+			if id != "" {
+				return id, "eventbridge"
+			}
+		}
+	}
+	return "", ""
+}
+
+func sqsCorrelationID(attributes map[string]events.SQSMessageAttribute, body string) string {
+	id := lookupCorrelationID(attributes, func(v events.SQSMessageAttribute) string {
+		if v.StringValue == nil {
+			return ""
+		}
+		return *v.StringValue
+	})
+	if id != "" {
+		return id
+	}
+	var envelope struct {
+		MessageAttributes map[string]snsMessageAttribute `json:"MessageAttributes"`
+	}
+	if strings.HasPrefix(strings.TrimSpace(body), "{") && json.Unmarshal([]byte(body), &envelope) == nil {
+		return snsCorrelationID(envelope.MessageAttributes)
+	}
+	return ""
+}
+
+func snsCorrelationID(attributes map[string]snsMessageAttribute) string {
+	return lookupCorrelationID(attributes, func(v snsMessageAttribute) string {
+		return v.Value
+	})
+}
+
+// correlationIDNames are the attribute and field names a correlation ID is read from, in order of preference,
+// ignoring case and separators
+var correlationIDNames = []string{"correlationid", "xcorrelationid"}
+
+// lookupCorrelationID returns the first non-empty value of the correlation ID names, e.g. correlationId,
+// correlation_id or X-Correlation-Id. Names are tried in the order of correlationIDNames, and keys which only differ
+// in case or separators in sorted order, so the ID does not depend on the order of the map.
+func lookupCorrelationID[V any](m map[string]V, value func(V) string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, name := range correlationIDNames {
+		for _, k := range keys {
+			if normalizeCorrelationIDName(k) != name {
+				continue
+			}
+			if v := value(m[k]); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func normalizeCorrelationIDName(name string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// traceRoot returns the Root field of an X-Amzn-Trace-Id header, e.g. Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1
+func traceRoot(header string) string {
+	for _, field := range strings.Split(header, ";") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(field), "="); ok && k == "Root" {
+			return v
+		}
+	}
+	return ""
+}
+
+// newCorrelationID generates a random version 4 UUID
+func newCorrelationID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// withResponseHeader adds a header to an HTTP response, without modifying the headers of the original response
+func withResponseHeader(res interface{}, key, value string) interface{} {
+	switch rsp := res.(type) {
+	case HTTPResponse:
+		rsp.Headers, rsp.MultiValueHeaders = addHeader(rsp.Headers, rsp.MultiValueHeaders, key, value)
+		return rsp
+	case *HTTPResponse:
+		if rsp == nil {
+			return res
+		}
+		copied := *rsp
+		copied.Headers, copied.MultiValueHeaders = addHeader(copied.Headers, copied.MultiValueHeaders, key, value)
+		return &copied
+	case events.APIGatewayProxyResponse:
+		rsp.Headers, rsp.MultiValueHeaders = addHeader(rsp.Headers, rsp.MultiValueHeaders, key, value)
+		return rsp
+	case events.ALBTargetGroupResponse:
+		rsp.Headers, rsp.MultiValueHeaders = addHeader(rsp.Headers, rsp.MultiValueHeaders, key, value)
+		return rsp
+	}
+	return res
+}
+
+// addHeader adds a header to the multi value headers if they are in use, as for ALB multi value responses,
+// and otherwise to the headers
+func addHeader(headers map[string]string, multiValueHeaders map[string][]string, key, value string) (map[string]string, map[string][]string) {
+	if len(multiValueHeaders) > 0 && len(headers) == 0 {
+		mvh := make(map[string][]string, len(multiValueHeaders)+1)
+		for k, v := range multiValueHeaders {
+			mvh[k] = v
+		}
+		mvh[key] = []string{value}
+		return headers, mvh
+	}
+	headers = copyHeaders(headers)
+	headers[key] = value
+	return headers, multiValueHeaders
+}
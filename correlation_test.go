@@ -0,0 +1,100 @@
+package vesper
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelationIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{name: "HTTP header", payload: `{"httpMethod": "GET", "path": "/", "headers": {"x-correlation-id": "abc"}}`, expected: "abc"},
+		{name: "HTTP trace header", payload: `{"httpMethod": "GET", "path": "/", "headers": {"X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"}}`, expected: "1-5759e988-bd862e3fe1be46a994272793"},
+		{name: "SQS message attribute", payload: `{"Records": [{"messageId": "1", "body": "{}"}, {"messageId": "2", "body": "{}", "messageAttributes": {"CorrelationId": {"stringValue": "abc", "dataType": "String"}}}]}`, expected: "abc"},
+		{name: "SNS envelope via SQS", payload: `{"Records": [{"messageId": "1", "body": "{\"Type\": \"Notification\", \"MessageAttributes\": {\"correlation-id\": {\"Type\": \"String\", \"Value\": \"abc\"}}}"}]}`, expected: "abc"},
+		{name: "SNS message attribute", payload: `{"Records": [{"Sns": {"Message": "{}", "MessageAttributes": {"correlationId": {"Type": "String", "Value": "abc"}}}}]}`, expected: "abc"},
+		{name: "EventBridge detail", payload: `{"detail-type": "UserCreated", "source": "users", "detail": {"correlationId": "abc"}}`, expected: "abc"},
+		{name: "preferred attribute", payload: `{"Records": [{"messageId": "1", "body": "{}", "messageAttributes": {"X-Correlation-Id": {"stringValue": "def", "dataType": "String"}, "correlation_id": {"stringValue": "abc", "dataType": "String"}, "correlationId": {"stringValue": "ghi", "dataType": "String"}}}]}`, expected: "ghi"},
+		{name: "preferred detail field", payload: `{"detail-type": "UserCreated", "source": "users", "detail": {"xCorrelationId": "def", "correlation-id": "abc"}}`, expected: "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := func(ctx context.Context) error {
+				got, _ = CorrelationIDFromContext(ctx)
+				return nil
+			}
+			_, err := New(h, CorrelationIDMiddleware()).buildHandler().Invoke(context.Background(), []byte(tt.payload))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("generated if missing", func(t *testing.T) {
+		var got string
+		h := func(ctx context.Context) error {
+			got, _ = CorrelationIDFromContext(ctx)
+			return nil
+		}
+		_, err := New(h, CorrelationIDMiddleware()).buildHandler().Invoke(context.Background(), []byte(`{"detail-type": "UserCreated", "detail": {}}`))
+		assert.NoError(t, err)
+		assert.Regexp(t, uuidRegexp, got)
+	})
+
+	t.Run("added to logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := func(ctx context.Context) error {
+			LoggerFromContext(ctx).Info("handled")
+			return nil
+		}
+		v := New(h, CorrelationIDMiddleware()).With(WithLogger(NewJSONLogger(&buf, slog.LevelInfo)))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"Records": [{"Sns": {"MessageAttributes": {"correlationId": {"Type": "String", "Value": "abc"}}}}]}`))
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), `"correlationId":"abc"`)
+	})
+
+	t.Run("per record", func(t *testing.T) {
+		var got []string
+		h := func(ctx context.Context, in string) error {
+			id, _ := CorrelationIDFromContext(ctx)
+			got = append(got, id)
+			return nil
+		}
+		event := `{"Records": [
+			{"messageId": "1", "body": "\"a\"", "messageAttributes": {"correlationId": {"stringValue": "first", "dataType": "String"}}},
+			{"messageId": "2", "body": "\"b\"", "messageAttributes": {"correlationId": {"stringValue": "second", "dataType": "String"}}}
+		]}`
+		v := New(h, JSONSQSRecordHandlerMiddleware(), CorrelationIDMiddleware()).DisableAutoUnmarshal()
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(event))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first", "second"}, got)
+	})
+
+	t.Run("HTTP response header", func(t *testing.T) {
+		headers := map[string]string{"Content-Type": "text/plain"}
+		h := func(ctx context.Context) (events.APIGatewayProxyResponse, error) {
+			return events.APIGatewayProxyResponse{StatusCode: 200, Headers: headers}, nil
+		}
+		rsp, err := New(h, CorrelationIDMiddleware()).buildHandler().Invoke(context.Background(), []byte(`{"httpMethod": "GET", "path": "/", "headers": {"X-Correlation-Id": "abc"}}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"statusCode": 200, "headers": {"Content-Type": "text/plain", "X-Correlation-Id": "abc"}, "multiValueHeaders": null, "body": ""}`, string(rsp))
+		assert.Equal(t, map[string]string{"Content-Type": "text/plain"}, headers)
+	})
+
+	t.Run("route response header", func(t *testing.T) {
+		r := NewRouter().Get("/users", func(ctx context.Context) (string, error) {
+			return "ok", nil
+		})
+		rsp, err := New(r.Serve, CorrelationIDMiddleware()).buildHandler().Invoke(context.Background(), []byte(`{"httpMethod": "GET", "path": "/users", "headers": {"X-Correlation-Id": "abc"}}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"statusCode": 200, "headers": {"Content-Type": "application/json", "X-Correlation-Id": "abc"}, "multiValueHeaders": null, "body": "\"ok\""}`, string(rsp))
+	})
+}
@@ -0,0 +1,169 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper/encoding"
+)
+
+// DynamoDBChangeRecord is a single change from a DynamoDB stream, with the item images converted into T.
+// OldImage and NewImage are nil if the stream view type does not include them, or the item did not exist
+// before an INSERT or after a REMOVE.
+type DynamoDBChangeRecord[T any] struct {
+	EventID   string
+	EventName string
+	Keys      map[string]events.DynamoDBAttributeValue
+	OldImage  *T
+	NewImage  *T
+}
+
+// dynamoDBChangeRecordDecoder is implemented by every DynamoDBChangeRecord so that
+// the middlewares can decode records without knowing T
+type dynamoDBChangeRecordDecoder interface {
+	decodeDynamoDBRecord(r events.DynamoDBEventRecord) error
+}
+
+func (c *DynamoDBChangeRecord[T]) decodeDynamoDBRecord(r events.DynamoDBEventRecord) error {
+	c.EventID = r.EventID
+	c.EventName = r.EventName
+	c.Keys = r.Change.Keys
+	decodeImage := func(name string, image map[string]events.DynamoDBAttributeValue) (*T, error) {
+		if len(image) == 0 {
+			return nil, nil
+		}
+		v := new(T)
+		if err := encoding.UnmarshalDynamoDBAttributes(image, v); err != nil {
+			return nil, fmt.Errorf("could not convert %s of DynamoDB record for sequence number %s: %w", name, r.Change.SequenceNumber, err)
+		}
+		return v, nil
+	}
+	var err error
+	if c.OldImage, err = decodeImage("OldImage", r.Change.OldImage); err != nil {
+		return err
+	}
+	if c.NewImage, err = decodeImage("NewImage", r.Change.NewImage); err != nil {
+		return err
+	}
+	return nil
+}
+
+func isDynamoDBChangeRecord(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(reflect.TypeOf((*dynamoDBChangeRecordDecoder)(nil)).Elem())
+}
+
+func decodeDynamoDBChangeRecord(t reflect.Type, r events.DynamoDBEventRecord) (interface{}, error) {
+	v := reflect.New(t)
+	if err := v.Interface().(dynamoDBChangeRecordDecoder).decodeDynamoDBRecord(r); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
+
+// DynamoDBStreamParserMiddleware transforms DynamoDB stream event records into the handler input parameter type,
+// converting the item images into the user defined type T of a slice of DynamoDBChangeRecord[T].
+// The handler input parameter must be a slice of DynamoDBChangeRecord, e.g. func(context.Context, []vesper.DynamoDBChangeRecord[User]) error
+func DynamoDBStreamParserMiddleware() func(LambdaFunc) LambdaFunc {
+	validateTIn := func(tIn reflect.Type) error {
+		if err := validateRecordsTIn("DynamoDBStreamParserMiddleware", "DynamoDB", tIn, reflect.TypeOf(events.DynamoDBEvent{})); err != nil {
+			return err
+		}
+		if !isDynamoDBChangeRecord(tIn.Elem()) {
+			return fmt.Errorf("input parameter for DynamoDB event must be a slice of DynamoDBChangeRecord but got %s", tIn.String())
+		}
+		return nil
+	}
+
+	unmarshalRecords := func(tIn reflect.Type, evt events.DynamoDBEvent) (reflect.Value, error) {
+		tIns := reflect.MakeSlice(tIn, 0, len(evt.Records))
+		for _, r := range evt.Records {
+			change, err := decodeDynamoDBChangeRecord(tIn.Elem(), r)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			tIns = reflect.Append(tIns, reflect.ValueOf(change))
+		}
+		return tIns, nil
+	}
+
+	return NamedMiddleware("DynamoDBStreamParserMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			tIn, ok := TInFromContext(ctx)
+			if !ok {
+				return next(ctx, in) // continue as there is no TIn to parse anyway.
+			}
+			if err := validateTIn(tIn); err != nil {
+				return nil, err
+			}
+			evt := events.DynamoDBEvent{}
+			if err := unmarshalEvent("DynamoDB", in, &evt); err != nil {
+				return nil, err
+			}
+			tIns, err := unmarshalRecords(tIn, evt)
+			if err != nil {
+				return nil, err
+			}
+			return next(ctx, tIns.Interface())
+		}
+	})
+}
+
+// DynamoDBRecordHandlerMiddleware calls the handler once per DynamoDB stream event record.
+// Records for which the handler returns an error are reported by sequence number in a BatchResponse.
+// The handler input parameter must be an events.DynamoDBEventRecord or a DynamoDBChangeRecord, and the handler response is ignored.
+// The DynamoDB record being processed can also be retrieved with DynamoDBRecordFromContext.
+func DynamoDBRecordHandlerMiddleware() func(LambdaFunc) LambdaFunc {
+	return ConcurrentDynamoDBRecordHandlerMiddleware(1)
+}
+
+// ConcurrentDynamoDBRecordHandlerMiddleware is the same as DynamoDBRecordHandlerMiddleware, but processes up to concurrency
+// records at the same time. Records for the same item are processed in order, and once one of them fails the
+// remaining records for that item are reported as failures without being processed.
+func ConcurrentDynamoDBRecordHandlerMiddleware(concurrency int) func(LambdaFunc) LambdaFunc {
+	recordType := reflect.TypeOf(events.DynamoDBEventRecord{})
+
+	validateTIn := func(tIn reflect.Type) error {
+		if tIn == reflect.TypeOf(events.DynamoDBEvent{}) {
+			return errors.New("DynamoDBRecordHandlerMiddleware middleware should not be used if input parameter is events.DynamoDBEvent")
+		}
+		if tIn != recordType && !isDynamoDBChangeRecord(tIn) {
+			return fmt.Errorf("input parameter for DynamoDB record must be %s or a DynamoDBChangeRecord", recordType.String())
+		}
+		return nil
+	}
+
+	parse := func(in interface{}) ([]batchRecord, error) {
+		evt := events.DynamoDBEvent{}
+		if err := unmarshalEvent("DynamoDB", in, &evt); err != nil {
+			return nil, err
+		}
+		records := make([]batchRecord, 0, len(evt.Records))
+		for _, r := range evt.Records {
+			r := r
+			group, err := json.Marshal(r.Change.Keys)
+			if err != nil {
+				return nil, fmt.Errorf("could not read keys of DynamoDB record for sequence number %s: %w", r.Change.SequenceNumber, err)
+			}
+			records = append(records, batchRecord{
+				id:    r.Change.SequenceNumber,
+				group: string(group),
+				withContext: func(ctx context.Context) context.Context {
+					return context.WithValue(ctx, ctxKeyDynamoDBRecord, r)
+				},
+				decode: func(tIn reflect.Type) (interface{}, error) {
+					if tIn == recordType {
+						return r, nil
+					}
+					return decodeDynamoDBChangeRecord(tIn, r)
+				},
+			})
+		}
+		return records, nil
+	}
+
+	return recordHandlerMiddleware("DynamoDBRecordHandlerMiddleware", concurrency, validateTIn, parse)
+}
@@ -0,0 +1,169 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamoDBRecordHandler(t *testing.T) {
+	request := `
+{
+  "Records": [
+    {
+      "eventID": "1",
+      "eventName": "INSERT",
+      "dynamodb": {
+        "Keys": {"Id": {"N": "101"}},
+        "NewImage": {"Id": {"N": "101"}, "Message": {"S": "New item!"}},
+        "SequenceNumber": "111"
+      }
+    },
+    {
+      "eventID": "2",
+      "eventName": "MODIFY",
+      "dynamodb": {
+        "Keys": {"Id": {"N": "101"}},
+        "NewImage": {"Id": {"N": "101"}, "Message": {"S": "This item has changed"}},
+        "OldImage": {"Id": {"N": "101"}, "Message": {"S": "New item!"}},
+        "SequenceNumber": "222"
+      }
+    }
+  ]
+}
+`
+
+	t.Run("TIn is not a DynamoDB record", func(t *testing.T) {
+		v := New(func(ctx context.Context, in string) error {
+			t.Error("unexpected call to handler")
+			return nil
+		}).DisableAutoUnmarshal().Use(DynamoDBRecordHandlerMiddleware())
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(request))
+		assert.Error(t, err)
+	})
+
+	t.Run("reports failed records", func(t *testing.T) {
+		var handled []string
+		h := func(ctx context.Context, r events.DynamoDBEventRecord) error {
+			fromCtx, ok := DynamoDBRecordFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, r.EventID, fromCtx.EventID)
+			handled = append(handled, r.EventName)
+			if r.EventName == "INSERT" {
+				return errors.New("something happened")
+			}
+			return nil
+		}
+		v := New(h).
+			DisableAutoUnmarshal().
+			Use(ConcurrentDynamoDBRecordHandlerMiddleware(2))
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(request))
+		assert.NoError(t, err)
+		// both records are for the same item, so the second is skipped once the first fails
+		assert.JSONEq(t, `{"batchItemFailures": [{"itemIdentifier": "111"}, {"itemIdentifier": "222"}]}`, string(rsp))
+		assert.Equal(t, []string{"INSERT"}, handled)
+	})
+}
+
+func TestDynamoDBStreamParser(t *testing.T) {
+	type item struct {
+		ID      int    `dynamodbav:"Id"`
+		Message string `dynamodbav:"Message"`
+	}
+	request := `
+{
+  "Records": [
+    {
+      "eventID": "1",
+      "eventName": "INSERT",
+      "dynamodb": {
+        "Keys": {"Id": {"N": "101"}},
+        "NewImage": {"Id": {"N": "101"}, "Message": {"S": "New item!"}},
+        "SequenceNumber": "111"
+      }
+    },
+    {
+      "eventID": "2",
+      "eventName": "REMOVE",
+      "dynamodb": {
+        "Keys": {"Id": {"N": "101"}},
+        "OldImage": {"Id": {"N": "101"}, "Message": {"S": "New item!"}},
+        "SequenceNumber": "222"
+      }
+    }
+  ]
+}
+`
+
+	t.Run("invalid TIn", func(t *testing.T) {
+		tests := []struct {
+			name string
+			tIn  reflect.Type
+		}{
+			{name: "TIn is not a slice", tIn: reflect.TypeOf(item{})},
+			{name: "TIn is of type events.DynamoDBEvent", tIn: reflect.TypeOf(events.DynamoDBEvent{})},
+			{name: "TIn is not a slice of change records", tIn: reflect.TypeOf([]item{})},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+					t.Errorf("unexpected call to next func")
+					return nil, nil
+				}
+				middleware := DynamoDBStreamParserMiddleware()(nextFunc)
+				ctx := context.WithValue(context.Background(), ctxKeyTIn, tt.tIn)
+				_, err := middleware(ctx, []byte(request))
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("image cannot be converted", func(t *testing.T) {
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := DynamoDBStreamParserMiddleware()(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf([]DynamoDBChangeRecord[item]{}))
+		_, err := middleware(ctx, []byte(`{"Records": [{"dynamodb": {"NewImage": {"Id": {"S": "not a number"}}}}]}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		called := false
+		h := func(ctx context.Context, changes []DynamoDBChangeRecord[item]) error {
+			called = true
+			keys := map[string]events.DynamoDBAttributeValue{"Id": events.NewNumberAttribute("101")}
+			assert.Equal(t, []DynamoDBChangeRecord[item]{
+				{EventID: "1", EventName: "INSERT", Keys: keys, NewImage: &item{ID: 101, Message: "New item!"}},
+				{EventID: "2", EventName: "REMOVE", Keys: keys, OldImage: &item{ID: 101, Message: "New item!"}},
+			}, changes)
+			return nil
+		}
+		v := New(h).
+			DisableAutoUnmarshal().
+			Use(DynamoDBStreamParserMiddleware())
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(request))
+		assert.NoError(t, err)
+		assert.True(t, called)
+	})
+
+	t.Run("record handler with change records", func(t *testing.T) {
+		var handled []string
+		h := func(ctx context.Context, change DynamoDBChangeRecord[item]) error {
+			handled = append(handled, change.EventName)
+			return nil
+		}
+		v := New(h).
+			DisableAutoUnmarshal().
+			Use(DynamoDBRecordHandlerMiddleware())
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(request))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"batchItemFailures": []}`, string(rsp))
+		assert.Equal(t, []string{"INSERT", "REMOVE"}, handled)
+	})
+}
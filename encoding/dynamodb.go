@@ -0,0 +1,268 @@
+package encoding
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// UnmarshalDynamoDBAttributes converts a DynamoDB item, such as the NewImage of a stream record, into the value pointed to by v.
+// Struct fields are matched using the `dynamodbav` struct tag, falling back to the `json` struct tag and then the field name.
+// A tag of "-" skips the field. The fields of untagged embedded structs, and pointers to them, are flattened into the
+// item as with encoding/json.
+func UnmarshalDynamoDBAttributes(item map[string]events.DynamoDBAttributeValue, v interface{}) error {
+	return UnmarshalDynamoDBAttribute(events.NewMapAttribute(item), v)
+}
+
+// UnmarshalDynamoDBAttribute converts a single DynamoDB attribute value into the value pointed to by v.
+func UnmarshalDynamoDBAttribute(av events.DynamoDBAttributeValue, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot unmarshal DynamoDB attribute into non-pointer %T", v)
+	}
+	return decodeAttribute(av, rv.Elem())
+}
+
+func decodeAttribute(av events.DynamoDBAttributeValue, rv reflect.Value) error {
+	if av.IsNull() {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeAttribute(av, rv.Elem())
+	}
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		val, err := attributeToInterface(av)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(val))
+		return nil
+	}
+	if av.DataType() == events.DataTypeString && reflect.PtrTo(rv.Type()).Implements(textUnmarshalerType) {
+		return rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(av.String()))
+	}
+
+	switch av.DataType() {
+	case events.DataTypeString:
+		if rv.Kind() != reflect.String {
+			return typeError(av, rv)
+		}
+		rv.SetString(av.String())
+	case events.DataTypeNumber:
+		return decodeNumber(av.Number(), rv)
+	case events.DataTypeBoolean:
+		if rv.Kind() != reflect.Bool {
+			return typeError(av, rv)
+		}
+		rv.SetBool(av.Boolean())
+	case events.DataTypeBinary:
+		if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() != reflect.Uint8 {
+			return typeError(av, rv)
+		}
+		rv.SetBytes(av.Binary())
+	case events.DataTypeList:
+		return decodeList(av.List(), rv)
+	case events.DataTypeMap:
+		return decodeMap(av.Map(), rv)
+	case events.DataTypeStringSet:
+		items := make([]events.DynamoDBAttributeValue, 0, len(av.StringSet()))
+		for _, s := range av.StringSet() {
+			items = append(items, events.NewStringAttribute(s))
+		}
+		return decodeList(items, rv)
+	case events.DataTypeNumberSet:
+		items := make([]events.DynamoDBAttributeValue, 0, len(av.NumberSet()))
+		for _, n := range av.NumberSet() {
+			items = append(items, events.NewNumberAttribute(n))
+		}
+		return decodeList(items, rv)
+	case events.DataTypeBinarySet:
+		items := make([]events.DynamoDBAttributeValue, 0, len(av.BinarySet()))
+		for _, b := range av.BinarySet() {
+			items = append(items, events.NewBinaryAttribute(b))
+		}
+		return decodeList(items, rv)
+	default:
+		return typeError(av, rv)
+	}
+	return nil
+}
+
+func decodeNumber(n string, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(n, 10, rv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal DynamoDB number %s into %s: %w", n, rv.Type(), err)
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(n, 10, rv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal DynamoDB number %s into %s: %w", n, rv.Type(), err)
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(n, rv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal DynamoDB number %s into %s: %w", n, rv.Type(), err)
+		}
+		rv.SetFloat(f)
+	case reflect.String:
+		rv.SetString(n)
+	default:
+		return fmt.Errorf("cannot unmarshal DynamoDB number into %s", rv.Type())
+	}
+	return nil
+}
+
+func decodeList(items []events.DynamoDBAttributeValue, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Slice:
+		rv.Set(reflect.MakeSlice(rv.Type(), len(items), len(items)))
+	case reflect.Array:
+		if rv.Len() < len(items) {
+			return fmt.Errorf("cannot unmarshal DynamoDB list of length %d into %s", len(items), rv.Type())
+		}
+	default:
+		return fmt.Errorf("cannot unmarshal DynamoDB list into %s", rv.Type())
+	}
+	for i, item := range items {
+		if err := decodeAttribute(item, rv.Index(i)); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func decodeMap(m map[string]events.DynamoDBAttributeValue, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot unmarshal DynamoDB map into %s", rv.Type())
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(m)))
+		}
+		for k, item := range m {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeAttribute(item, elem); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), elem)
+		}
+		return nil
+	case reflect.Struct:
+		return decodeStruct(m, rv)
+	default:
+		return fmt.Errorf("cannot unmarshal DynamoDB map into %s", rv.Type())
+	}
+}
+
+func decodeStruct(m map[string]events.DynamoDBAttributeValue, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, tagged := attributeName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && !tagged && field.Type.Kind() == reflect.Struct {
+			if err := decodeStruct(m, rv.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Anonymous && !tagged && field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct {
+			if field.PkgPath != "" {
+				continue // the pointer to an unexported struct cannot be set
+			}
+			f := rv.Field(i)
+			if f.IsNil() {
+				f.Set(reflect.New(field.Type.Elem()))
+			}
+			if err := decodeStruct(m, f.Elem()); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		item, ok := m[name]
+		if !ok {
+			continue
+		}
+		if err := decodeAttribute(item, rv.Field(i)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// attributeName returns the attribute name of a struct field, and whether it was set by a struct tag
+func attributeName(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"dynamodbav", "json"} {
+		if tag, ok := field.Tag.Lookup(key); ok {
+			name := strings.Split(tag, ",")[0]
+			if name != "" {
+				return name, true
+			}
+		}
+	}
+	return field.Name, false
+}
+
+func attributeToInterface(av events.DynamoDBAttributeValue) (interface{}, error) {
+	switch av.DataType() {
+	case events.DataTypeString:
+		return av.String(), nil
+	case events.DataTypeNumber:
+		return strconv.ParseFloat(av.Number(), 64)
+	case events.DataTypeBoolean:
+		return av.Boolean(), nil
+	case events.DataTypeBinary:
+		return av.Binary(), nil
+	case events.DataTypeStringSet:
+		return av.StringSet(), nil
+	case events.DataTypeBinarySet:
+		return av.BinarySet(), nil
+	case events.DataTypeNumberSet, events.DataTypeList:
+		var l []interface{}
+		err := decodeAttribute(av, reflect.ValueOf(&l).Elem())
+		return l, err
+	case events.DataTypeMap:
+		m := map[string]interface{}{}
+		err := decodeMap(av.Map(), reflect.ValueOf(m))
+		return m, err
+	default:
+		return nil, nil
+	}
+}
+
+var dataTypeNames = map[events.DynamoDBDataType]string{
+	events.DataTypeBinary:    "B",
+	events.DataTypeBoolean:   "BOOL",
+	events.DataTypeBinarySet: "BS",
+	events.DataTypeList:      "L",
+	events.DataTypeMap:       "M",
+	events.DataTypeNumber:    "N",
+	events.DataTypeNumberSet: "NS",
+	events.DataTypeNull:      "NULL",
+	events.DataTypeString:    "S",
+	events.DataTypeStringSet: "SS",
+}
+
+func typeError(av events.DynamoDBAttributeValue, rv reflect.Value) error {
+	return fmt.Errorf("cannot unmarshal DynamoDB attribute of type %s into %s", dataTypeNames[av.DataType()], rv.Type())
+}
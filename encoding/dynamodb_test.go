@@ -0,0 +1,117 @@
+package encoding
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalDynamoDBAttributes(t *testing.T) {
+	type address struct {
+		Street string `json:"street"`
+	}
+	type base struct {
+		ID string `dynamodbav:"pk"`
+	}
+	type user struct {
+		base
+		Name      string            `json:"name"`
+		Age       int               `dynamodbav:"age,omitempty"`
+		Score     float64           `json:"score"`
+		Admin     bool              `json:"admin"`
+		Avatar    []byte            `json:"avatar"`
+		Tags      []string          `json:"tags"`
+		Lucky     []int             `json:"lucky"`
+		Address   *address          `json:"address"`
+		Labels    map[string]string `json:"labels"`
+		Extra     interface{}       `json:"extra"`
+		CreatedAt time.Time         `json:"createdAt"`
+		Ignored   string            `dynamodbav:"-"`
+		Missing   string
+	}
+
+	item := map[string]events.DynamoDBAttributeValue{}
+	err := json.Unmarshal([]byte(`
+{
+  "pk": {"S": "user#1"},
+  "name": {"S": "myuser"},
+  "age": {"N": "20"},
+  "score": {"N": "9.5"},
+  "admin": {"BOOL": true},
+  "avatar": {"B": "aGVsbG8="},
+  "tags": {"SS": ["a", "b"]},
+  "lucky": {"NS": ["7", "13"]},
+  "address": {"M": {"street": {"S": "1 Main St"}}},
+  "labels": {"M": {"team": {"S": "core"}}},
+  "extra": {"L": [{"S": "x"}, {"N": "1"}, {"NULL": true}]},
+  "createdAt": {"S": "2020-04-01T10:00:00Z"},
+  "Ignored": {"S": "should not be set"}
+}
+`), &item)
+	assert.NoError(t, err)
+
+	var u user
+	err = UnmarshalDynamoDBAttributes(item, &u)
+	assert.NoError(t, err)
+	assert.Equal(t, user{
+		base:      base{ID: "user#1"},
+		Name:      "myuser",
+		Age:       20,
+		Score:     9.5,
+		Admin:     true,
+		Avatar:    []byte("hello"),
+		Tags:      []string{"a", "b"},
+		Lucky:     []int{7, 13},
+		Address:   &address{Street: "1 Main St"},
+		Labels:    map[string]string{"team": "core"},
+		Extra:     []interface{}{"x", float64(1), nil},
+		CreatedAt: time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC),
+	}, u)
+}
+
+func TestUnmarshalDynamoDBAttributesEmbeddedPointer(t *testing.T) {
+	type Audit struct {
+		UpdatedBy string `json:"updatedBy"`
+	}
+	type hidden struct {
+		Secret string `json:"secret"`
+	}
+	type order struct {
+		*Audit
+		*hidden
+		ID string `dynamodbav:"pk"`
+	}
+
+	item := map[string]events.DynamoDBAttributeValue{
+		"pk":        events.NewStringAttribute("order#1"),
+		"updatedBy": events.NewStringAttribute("bob"),
+		"secret":    events.NewStringAttribute("hunter2"),
+	}
+	var o order
+	err := UnmarshalDynamoDBAttributes(item, &o)
+	assert.NoError(t, err)
+	// as with encoding/json, the pointer to an unexported struct is not set
+	assert.Equal(t, order{Audit: &Audit{UpdatedBy: "bob"}, ID: "order#1"}, o)
+}
+
+func TestUnmarshalDynamoDBAttributeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		av   events.DynamoDBAttributeValue
+		v    interface{}
+	}{
+		{name: "non-pointer", av: events.NewStringAttribute("a"), v: ""},
+		{name: "string into int", av: events.NewStringAttribute("a"), v: new(int)},
+		{name: "number overflow", av: events.NewNumberAttribute("300"), v: new(int8)},
+		{name: "list into struct", av: events.NewListAttribute(nil), v: &struct{}{}},
+		{name: "map into slice", av: events.NewMapAttribute(nil), v: new([]string)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, UnmarshalDynamoDBAttribute(tt.av, tt.v))
+		})
+	}
+}
@@ -0,0 +1,4 @@
+package encoding
+
+// MarshalFunc converts a value into bytes, such as json.Marshal
+type MarshalFunc func(v interface{}) ([]byte, error)
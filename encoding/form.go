@@ -0,0 +1,248 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FormFile is a file uploaded in a multipart form
+type FormFile struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+var (
+	formFileType = reflect.TypeOf(FormFile{})
+	bytesType    = reflect.TypeOf([]byte{})
+)
+
+// UnmarshalForm decodes an application/x-www-form-urlencoded body into the value pointed to by v, which must be a
+// struct, a map[string]string or a map[string][]string.
+// Struct fields are matched using the `form` struct tag, falling back to the `json` struct tag and then the field name.
+// A tag of "-" skips the field. Fields may be strings, booleans, numbers, encoding.TextUnmarshalers, or slices of them
+// to receive every value of a repeated key.
+func UnmarshalForm(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return fmt.Errorf("could not parse form: %w", err)
+	}
+	return decodeForm(values, nil, v)
+}
+
+// UnmarshalMultipartForm decodes a multipart/form-data body into the value pointed to by v, in the same way as UnmarshalForm.
+// The boundary is the boundary parameter of the Content-Type of the body. Uploaded files are decoded into fields of type
+// FormFile, *FormFile, []FormFile or []byte (the file content).
+func UnmarshalMultipartForm(data []byte, boundary string, v interface{}) error {
+	if boundary == "" {
+		return errors.New("multipart form boundary is missing")
+	}
+	values := map[string][]string{}
+	files := map[string][]FormFile{}
+	r := multipart.NewReader(bytes.NewReader(data), boundary)
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not read multipart form: %w", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return fmt.Errorf("could not read multipart form part '%s': %w", part.FormName(), err)
+		}
+		if part.FileName() != "" {
+			files[part.FormName()] = append(files[part.FormName()], FormFile{
+				Filename:    part.FileName(),
+				ContentType: part.Header.Get("Content-Type"),
+				Content:     content,
+			})
+			continue
+		}
+		values[part.FormName()] = append(values[part.FormName()], string(content))
+	}
+	return decodeForm(values, files, v)
+}
+
+// MultipartFormUnmarshaler returns an UnmarshalFunc decoding multipart/form-data bodies with the given boundary
+func MultipartFormUnmarshaler(boundary string) UnmarshalFunc {
+	return func(data []byte, v interface{}) error {
+		return UnmarshalMultipartForm(data, boundary, v)
+	}
+}
+
+func decodeForm(values map[string][]string, files map[string][]FormFile, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot unmarshal form into non-pointer %T", v)
+	}
+	rv = rv.Elem()
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		m := reflect.MakeMapWithSize(rv.Type(), len(values))
+		for k, vs := range values {
+			switch rv.Type().Elem().Kind() {
+			case reflect.String:
+				m.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), reflect.ValueOf(vs[0]).Convert(rv.Type().Elem()))
+			case reflect.Slice:
+				if rv.Type().Elem().Elem().Kind() != reflect.String {
+					return fmt.Errorf("cannot unmarshal form into %s", rv.Type())
+				}
+				m.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), reflect.ValueOf(vs).Convert(rv.Type().Elem()))
+			default:
+				return fmt.Errorf("cannot unmarshal form into %s", rv.Type())
+			}
+		}
+		rv.Set(m)
+		return nil
+	case reflect.Struct:
+		return decodeFormStruct(values, files, rv)
+	}
+	return fmt.Errorf("cannot unmarshal form into %s", rv.Type())
+}
+
+func decodeFormStruct(values map[string][]string, files map[string][]FormFile, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := rv.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := decodeFormStruct(values, files, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name := formFieldName(f)
+		if name == "-" {
+			continue
+		}
+
+		if fs, ok := files[name]; ok && isFileField(f.Type) {
+			setFormFile(fv, fs)
+			continue
+		}
+		vs, ok := values[name]
+		if !ok || len(vs) == 0 {
+			continue
+		}
+		if err := setFormValues(fv, vs); err != nil {
+			return fmt.Errorf("could not unmarshal form field '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+func formFieldName(f reflect.StructField) string {
+	for _, key := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func isFileField(t reflect.Type) bool {
+	switch t {
+	case formFileType, reflect.PtrTo(formFileType), reflect.SliceOf(formFileType), bytesType:
+		return true
+	}
+	return false
+}
+
+func setFormFile(fv reflect.Value, fs []FormFile) {
+	switch fv.Type() {
+	case formFileType:
+		fv.Set(reflect.ValueOf(fs[0]))
+	case reflect.PtrTo(formFileType):
+		f := fs[0]
+		fv.Set(reflect.ValueOf(&f))
+	case reflect.SliceOf(formFileType):
+		fv.Set(reflect.ValueOf(fs))
+	case bytesType:
+		fv.SetBytes(fs[0].Content)
+	}
+}
+
+func setFormValues(fv reflect.Value, vs []string) error {
+	if fv.Kind() == reflect.Slice && fv.Type() != bytesType && !reflect.PtrTo(fv.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+		for i, v := range vs {
+			if err := setFormValue(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+	return setFormValue(fv, vs[0])
+}
+
+func setFormValue(fv reflect.Value, v string) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setFormValue(fv.Elem(), v)
+	}
+	if reflect.PtrTo(fv.Type()).Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(v)
+	case reflect.Bool:
+		// checkboxes are submitted as "on"
+		if v == "on" {
+			fv.SetBool(true)
+			return nil
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(v, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Interface:
+		if fv.NumMethod() != 0 {
+			return fmt.Errorf("cannot unmarshal into %s", fv.Type())
+		}
+		fv.Set(reflect.ValueOf(v))
+	default:
+		return fmt.Errorf("cannot unmarshal into %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package encoding
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type formBase struct {
+	ID int `form:"id"`
+}
+
+type signup struct {
+	formBase
+	Name     string    `form:"name"`
+	Email    string    `json:"email"`
+	Age      *int      `form:"age"`
+	Score    float64   `form:"score"`
+	Agree    bool      `form:"agree"`
+	Tags     []string  `form:"tag"`
+	Born     time.Time `form:"born"`
+	Ignored  string    `form:"-"`
+	Avatar   *FormFile `form:"avatar"`
+	Document []byte    `form:"document"`
+}
+
+func TestUnmarshalForm(t *testing.T) {
+	var s signup
+	err := UnmarshalForm([]byte("id=7&name=Bob+Smith&email=bob%40example.com&age=30&score=1.5&agree=on&tag=a&tag=b&born=2000-01-02T00:00:00Z&Ignored=x"), &s)
+	assert.NoError(t, err)
+	age := 30
+	assert.Equal(t, signup{
+		formBase: formBase{ID: 7},
+		Name:     "Bob Smith",
+		Email:    "bob@example.com",
+		Age:      &age,
+		Score:    1.5,
+		Agree:    true,
+		Tags:     []string{"a", "b"},
+		Born:     time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+	}, s)
+
+	var m map[string][]string
+	assert.NoError(t, UnmarshalForm([]byte("a=1&a=2&b=3"), &m))
+	assert.Equal(t, map[string][]string{"a": {"1", "2"}, "b": {"3"}}, m)
+
+	var single map[string]string
+	assert.NoError(t, UnmarshalForm([]byte("a=1&a=2&b=3"), &single))
+	assert.Equal(t, map[string]string{"a": "1", "b": "3"}, single)
+
+	assert.EqualError(t, UnmarshalForm([]byte("age=old"), &s), `could not unmarshal form field 'age': strconv.ParseInt: parsing "old": invalid syntax`)
+	assert.EqualError(t, UnmarshalForm([]byte("a=1"), s), "cannot unmarshal form into non-pointer encoding.signup")
+}
+
+func TestUnmarshalMultipartForm(t *testing.T) {
+	body := strings.ReplaceAll(`--XYZ
+Content-Disposition: form-data; name="name"
+
+Bob
+--XYZ
+Content-Disposition: form-data; name="tag"
+
+a
+--XYZ
+Content-Disposition: form-data; name="tag"
+
+b
+--XYZ
+Content-Disposition: form-data; name="avatar"; filename="bob.png"
+Content-Type: image/png
+
+PNG
+--XYZ
+Content-Disposition: form-data; name="document"; filename="cv.txt"
+Content-Type: text/plain
+
+hello
+--XYZ--
+`, "\n", "\r\n")
+
+	var s signup
+	assert.NoError(t, UnmarshalMultipartForm([]byte(body), "XYZ", &s))
+	assert.Equal(t, "Bob", s.Name)
+	assert.Equal(t, []string{"a", "b"}, s.Tags)
+	assert.Equal(t, &FormFile{Filename: "bob.png", ContentType: "image/png", Content: []byte("PNG")}, s.Avatar)
+	assert.Equal(t, []byte("hello"), s.Document)
+
+	assert.EqualError(t, UnmarshalMultipartForm([]byte(body), "", &s), "multipart form boundary is missing")
+	assert.Error(t, UnmarshalMultipartForm([]byte(body), "ABC", &s))
+
+	// the boundary comes from the Content-Type, even when the body starts with a preamble
+	var p signup
+	preamble := "This is a preamble\r\n--XYZ\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nAlice\r\n--XYZ--\r\n"
+	assert.NoError(t, MultipartFormUnmarshaler("XYZ")([]byte(preamble), &p))
+	assert.Equal(t, "Alice", p.Name)
+}
@@ -0,0 +1,32 @@
+package encoding
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MarshalMsgPack converts a value into MessagePack (https://msgpack.org), a compact binary alternative to JSON.
+// Struct fields are named using the `json` struct tag (falling back to the `msgpack` tag), so responses have the
+// same field names as their JSON equivalent. Integers are encoded in the smallest representation, and the keys of
+// map[string]interface{} and map[string]string values are sorted.
+// Types with a custom JSON marshaler are encoded by their fields, as they would be without the marshaler; implement
+// msgpack.CustomEncoder to change their encoding.
+func MarshalMsgPack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalMsgPack converts MessagePack into a value, matching struct fields by their `json` struct tag
+func UnmarshalMsgPack(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,73 @@
+package encoding
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalMsgPack(t *testing.T) {
+	type user struct {
+		Name   string   `json:"name"`
+		Age    int      `json:"age"`
+		Score  float64  `json:"score"`
+		Admin  bool     `json:"admin"`
+		Tags   []string `json:"tags"`
+		Parent *user    `json:"parent"`
+	}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected []byte
+	}{
+		{name: "nil", value: nil, expected: []byte{0xc0}},
+		{name: "positive fixint", value: 7, expected: []byte{0x07}},
+		{name: "negative fixint", value: -3, expected: []byte{0xfd}},
+		{name: "int8", value: -100, expected: []byte{0xd0, 0x9c}},
+		{name: "int16", value: -1000, expected: []byte{0xd1, 0xfc, 0x18}},
+		{name: "uint16", value: 1000, expected: []byte{0xcd, 0x03, 0xe8}},
+		{name: "uint32", value: 100000, expected: []byte{0xce, 0x00, 0x01, 0x86, 0xa0}},
+		{name: "uint64", value: int64(1) << 40, expected: []byte{0xcf, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{name: "float", value: 1.5, expected: []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{name: "map", value: map[string]interface{}{"b": 2, "a": 1}, expected: []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+		{name: "str8", value: strings.Repeat("a", 40), expected: append([]byte{0xd9, 40}, []byte(strings.Repeat("a", 40))...)},
+		{
+			name:  "struct",
+			value: user{Name: "Bob", Age: 30, Score: 0.5, Admin: true, Tags: []string{"a"}},
+			expected: []byte{
+				0x86,
+				0xa4, 'n', 'a', 'm', 'e', 0xa3, 'B', 'o', 'b',
+				0xa3, 'a', 'g', 'e', 0x1e,
+				0xa5, 's', 'c', 'o', 'r', 'e', 0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0,
+				0xa5, 'a', 'd', 'm', 'i', 'n', 0xc3,
+				0xa4, 't', 'a', 'g', 's', 0x91, 0xa1, 'a',
+				0xa6, 'p', 'a', 'r', 'e', 'n', 't', 0xc0,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := MarshalMsgPack(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, b)
+		})
+	}
+
+	_, err := MarshalMsgPack(make(chan int))
+	assert.Error(t, err)
+}
+
+func TestUnmarshalMsgPack(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age,omitempty"`
+	}
+	b, err := MarshalMsgPack(user{Name: "Bob", Age: 30})
+	assert.NoError(t, err)
+
+	var u user
+	assert.NoError(t, UnmarshalMsgPack(b, &u))
+	assert.Equal(t, user{Name: "Bob", Age: 30}, u)
+}
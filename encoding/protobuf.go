@@ -0,0 +1,26 @@
+package encoding
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// MarshalProtobuf converts a proto.Message into the protocol buffers wire format.
+// Values which are not a proto.Message cannot be marshaled, and return an error.
+func MarshalProtobuf(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T as protobuf, as it is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// UnmarshalProtobuf converts the protocol buffers wire format into the proto.Message v
+func UnmarshalProtobuf(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal protobuf into %T, as it is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
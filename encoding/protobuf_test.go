@@ -0,0 +1,26 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMarshalProtobuf(t *testing.T) {
+	b, err := MarshalProtobuf(wrapperspb.String("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("\x0a\x05hello"), b)
+
+	_, err = MarshalProtobuf(map[string]string{"name": "hello"})
+	assert.EqualError(t, err, "cannot marshal map[string]string as protobuf, as it is not a proto.Message")
+}
+
+func TestUnmarshalProtobuf(t *testing.T) {
+	var s wrapperspb.StringValue
+	assert.NoError(t, UnmarshalProtobuf([]byte("\x0a\x05hello"), &s))
+	assert.Equal(t, "hello", s.GetValue())
+
+	var m map[string]string
+	assert.EqualError(t, UnmarshalProtobuf([]byte("\x0a\x05hello"), &m), "cannot unmarshal protobuf into *map[string]string, as it is not a proto.Message")
+}
@@ -0,0 +1,190 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// EventSource identifies the AWS service or client an event was received from
+type EventSource string
+
+const (
+	// EventSourceAPIGateway is an API Gateway REST API proxy integration (payload format 1.0)
+	EventSourceAPIGateway = EventSource(HTTPEventSourceAPIGateway)
+	// EventSourceAPIGatewayV2 is an API Gateway HTTP API integration (payload format 2.0)
+	EventSourceAPIGatewayV2 = EventSource(HTTPEventSourceAPIGatewayV2)
+	// EventSourceALB is an Application Load Balancer target group
+	EventSourceALB = EventSource(HTTPEventSourceALB)
+	// EventSourceSQS is an SQS queue, events.SQSEvent
+	EventSourceSQS EventSource = "sqs"
+	// EventSourceSNS is an SNS topic, events.SNSEvent
+	EventSourceSNS EventSource = "sns"
+	// EventSourceKinesis is a Kinesis stream, events.KinesisEvent
+	EventSourceKinesis EventSource = "kinesis"
+	// EventSourceDynamoDB is a DynamoDB stream, events.DynamoDBEvent
+	EventSourceDynamoDB EventSource = "dynamodb"
+	// EventSourceS3 is an S3 event notification, events.S3Event
+	EventSourceS3 EventSource = "s3"
+	// EventSourceEventBridge is an EventBridge or CloudWatch Events event, events.CloudWatchEvent
+	EventSourceEventBridge EventSource = "eventbridge"
+	// EventSourceSchedule is a scheduled EventBridge event, events.CloudWatchEvent with a detail type of "Scheduled Event"
+	EventSourceSchedule EventSource = "schedule"
+	// EventSourceWarmup is a warmup event from serverless-plugin-warmup, see WarmupMiddleware
+	EventSourceWarmup EventSource = "warmup"
+	// EventSourceDirect is any other payload, such as from a direct invocation
+	EventSourceDirect EventSource = "direct"
+)
+
+// sourcedEvent is the subset of the supported events which identifies their source
+type sourcedEvent struct {
+	Records []struct {
+		EventSource    string `json:"eventSource"`
+		SNSEventSource string `json:"EventSource"`
+	} `json:"Records"`
+	Source     string `json:"source"`
+	DetailType string `json:"detail-type"`
+	Event      struct {
+		Source string `json:"source"`
+	} `json:"Event"`
+}
+
+// DetectEventSource detects the source of an event from its payload. Payloads which are not an event of one of
+// the supported sources are EventSourceDirect.
+func DetectEventSource(payload []byte) EventSource {
+	var req HTTPRequest
+	if err := json.Unmarshal(payload, &req); err == nil && req.Source != "" {
+		return EventSource(req.Source)
+	}
+	var event sourcedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return EventSourceDirect
+	}
+	if len(event.Records) > 0 {
+		switch event.Records[0].EventSource + event.Records[0].SNSEventSource {
+		case "aws:sqs":
+			return EventSourceSQS
+		case "aws:sns":
+			return EventSourceSNS
+		case "aws:kinesis":
+			return EventSourceKinesis
+		case "aws:dynamodb":
+			return EventSourceDynamoDB
+		case "aws:s3":
+			return EventSourceS3
+		}
+	}
+	switch {
+	case event.DetailType == "Scheduled Event":
+		return EventSourceSchedule
+	case event.DetailType != "" && event.Source != "":
+		return EventSourceEventBridge
+	case event.Event.Source == "serverless-plugin-warmup":
+		return EventSourceWarmup
+	}
+	return EventSourceDirect
+}
+
+// EventRouter dispatches events to the handler registered for the source of the event, for functions which are
+// triggered by more than one source. Each source has its own handler and middleware chain, and events from sources
+// without a handler are given to the default handler.
+//
+// An EventRouter is used as the handler of a Vesper instance, so middleware given to Vesper wraps every source:
+//
+//	r := vesper.NewEventRouter().
+//		HTTP(httpRouter.Serve).
+//		Handle(vesper.EventSourceSQS, CreateUserHandler, vesper.JSONSQSRecordHandlerMiddleware()).
+//		Handle(vesper.EventSourceSchedule, CleanupHandler).
+//		Default(DirectHandler)
+//	vesper.New(r.Serve, vesper.CorrelationIDMiddleware()).Start()
+type EventRouter struct {
+	sources  map[EventSource]*eventRoute
+	fallback *eventRoute
+}
+
+type eventRoute struct {
+	tIn     reflect.Type
+	handler LambdaFunc
+}
+
+// NewEventRouter creates a new EventRouter with no handlers
+func NewEventRouter() *EventRouter {
+	return &EventRouter{sources: map[EventSource]*eventRoute{}}
+}
+
+// Handle registers a handler and middlewares for events from the source, replacing any registered before.
+//
+// The handler may have any signature accepted by New, and the event is JSON unmarshaled into its input parameter,
+// e.g. events.SQSEvent. Source middlewares receive the payload as []byte, as with WithoutAutoUnmarshal, so record
+// handler middlewares such as JSONSQSRecordHandlerMiddleware can be used with a handler taking a single record.
+// Middleware which needs the unmarshaled input, such as ValidatorMiddleware, should follow JSONParserMiddleware.
+func (r *EventRouter) Handle(source EventSource, handler interface{}, middlewares ...Middleware) *EventRouter {
+	r.sources[source] = newEventRoute(handler, middlewares)
+	return r
+}
+
+// HTTP registers a handler and middlewares for requests from API Gateway REST and HTTP APIs and ALBs, such as the
+// Serve function of a Router. See Handle.
+func (r *EventRouter) HTTP(handler interface{}, middlewares ...Middleware) *EventRouter {
+	route := newEventRoute(handler, middlewares)
+	r.sources[EventSourceAPIGateway] = route
+	r.sources[EventSourceAPIGatewayV2] = route
+	r.sources[EventSourceALB] = route
+	return r
+}
+
+// Default registers a handler and middlewares for events from sources without a handler. See Handle.
+func (r *EventRouter) Default(handler interface{}, middlewares ...Middleware) *EventRouter {
+	r.fallback = newEventRoute(handler, middlewares)
+	return r
+}
+
+// Serve dispatches the event to the handler of its source, and is the handler to give to New. The source of the
+// event is available to the handler via EventSourceFromContext. An error is returned if the source has no handler
+// and there is no default handler.
+func (r *EventRouter) Serve(ctx context.Context, _ interface{}) (interface{}, error) {
+	payload, ok := PayloadFromContext(ctx)
+	if !ok {
+		return nil, errors.New("event router expected the invocation payload in the context")
+	}
+	source := DetectEventSource(payload)
+	route, ok := r.sources[source]
+	if !ok {
+		route = r.fallback
+	}
+	if route == nil {
+		return nil, fmt.Errorf("no handler is registered for %s events", source)
+	}
+
+	ctx = context.WithValue(ctx, ctxKeyEventSource, source)
+	ctx = context.WithValue(ctx, ctxKeyTIn, route.tIn)
+	return route.handler(ctx, payload)
+}
+
+func newEventRoute(handler interface{}, middlewares []Middleware) *eventRoute {
+	return &eventRoute{
+		tIn:     handlerInputType(handler),
+		handler: buildChain(bindPayload(newTypedToUntypedWrapper(handler)), middlewares...),
+	}
+}
+
+// bindPayload unmarshals the payload given to an event route into the handler input parameter type
+func bindPayload(next LambdaFunc) LambdaFunc {
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		b, ok := in.([]byte)
+		if !ok {
+			return next(ctx, in) // a route middleware has already converted the payload
+		}
+		tIn, _ := TInFromContext(ctx)
+		if tIn == nil || tIn == reflect.TypeOf(b) {
+			return next(ctx, in)
+		}
+		v, err := unmarshalToType(json.Unmarshal, tIn, b)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal payload to type of '%s': %w", tIn.String(), err)
+		}
+		return next(ctx, v)
+	}
+}
@@ -0,0 +1,122 @@
+package vesper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectEventSource(t *testing.T) {
+	tests := []struct {
+		payload  string
+		expected EventSource
+	}{
+		{`{"httpMethod": "GET", "path": "/users"}`, EventSourceAPIGateway},
+		{`{"version": "2.0", "rawPath": "/users", "requestContext": {"http": {"method": "GET"}}}`, EventSourceAPIGatewayV2},
+		{`{"httpMethod": "GET", "path": "/users", "requestContext": {"elb": {"targetGroupArn": "arn"}}}`, EventSourceALB},
+		{`{"Records": [{"messageId": "1", "eventSource": "aws:sqs"}]}`, EventSourceSQS},
+		{`{"Records": [{"EventSource": "aws:sns", "Sns": {"Message": "{}"}}]}`, EventSourceSNS},
+		{`{"Records": [{"eventSource": "aws:kinesis"}]}`, EventSourceKinesis},
+		{`{"Records": [{"eventSource": "aws:dynamodb", "eventName": "INSERT"}]}`, EventSourceDynamoDB},
+		{`{"Records": [{"eventSource": "aws:s3"}]}`, EventSourceS3},
+		{`{"source": "users", "detail-type": "UserCreated", "detail": {}}`, EventSourceEventBridge},
+		{`{"source": "aws.events", "detail-type": "Scheduled Event", "detail": {}}`, EventSourceSchedule},
+		{`{"Event": {"source": "serverless-plugin-warmup"}}`, EventSourceWarmup},
+		{`{"name": "bob"}`, EventSourceDirect},
+		{`{"Records": [{"eventSource": "aws:unknown"}]}`, EventSourceDirect},
+		{`"hello"`, EventSourceDirect},
+		{`not json`, EventSourceDirect},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, DetectEventSource([]byte(tc.payload)), tc.payload)
+	}
+}
+
+func TestEventRouter(t *testing.T) {
+	type user struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	var calls []string
+	var sources []EventSource
+	record := func(name string) {
+		calls = append(calls, name)
+	}
+	httpRouter := NewRouter().Post("/users", func(ctx context.Context, u user) (HTTPResponse, error) {
+		record("http " + u.Name)
+		return HTTPResponse{StatusCode: 201}, nil
+	})
+	var routeMiddlewareInput interface{}
+	routeMiddleware := func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			routeMiddlewareInput = in
+			return next(ctx, in)
+		}
+	}
+	router := NewEventRouter().
+		HTTP(httpRouter.Serve).
+		Handle(EventSourceSQS, func(ctx context.Context, u user) error {
+			record("sqs " + u.Name)
+			return nil
+		}, routeMiddleware, JSONSQSRecordHandlerMiddleware()).
+		Handle(EventSourceEventBridge, func(ctx context.Context, e events.CloudWatchEvent) error {
+			record("eventbridge " + e.DetailType)
+			return nil
+		}, JSONParserMiddleware(), ValidatorMiddleware()).
+		Default(func(ctx context.Context, u user) (string, error) {
+			source, _ := EventSourceFromContext(ctx)
+			sources = append(sources, source)
+			return "hello " + u.Name, nil
+		})
+	v := New(router.Serve, CorrelationIDMiddleware())
+
+	t.Run("http", func(t *testing.T) {
+		for _, payload := range []string{
+			`{"httpMethod": "POST", "path": "/users", "body": "{\"name\": \"bob\"}"}`,
+			`{"version": "2.0", "rawPath": "/users", "requestContext": {"http": {"method": "POST"}}, "body": "{\"name\": \"bob\"}"}`,
+		} {
+			rsp, err := v.Invoke(context.Background(), []byte(payload))
+			assert.NoError(t, err)
+			assert.Contains(t, string(rsp), `"statusCode":201`)
+		}
+		assert.Equal(t, []string{"http bob", "http bob"}, calls)
+		calls = nil
+	})
+
+	t.Run("record handler middleware", func(t *testing.T) {
+		rsp, err := v.Invoke(context.Background(), []byte(`{"Records": [
+			{"messageId": "1", "eventSource": "aws:sqs", "body": "{\"name\": \"bob\"}"},
+			{"messageId": "2", "eventSource": "aws:sqs", "body": "{\"name\": \"alice\"}"}
+		]}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"batchItemFailures": []}`, string(rsp))
+		assert.Equal(t, []string{"sqs bob", "sqs alice"}, calls)
+		assert.IsType(t, []byte{}, routeMiddlewareInput)
+		calls = nil
+	})
+
+	t.Run("typed event", func(t *testing.T) {
+		_, err := v.Invoke(context.Background(), []byte(`{"source": "users", "detail-type": "UserCreated", "detail": {}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"eventbridge UserCreated"}, calls)
+		calls = nil
+	})
+
+	t.Run("default handler", func(t *testing.T) {
+		rsp, err := v.Invoke(context.Background(), []byte(`{"name": "bob"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"hello bob"`, string(rsp))
+		_, err = v.Invoke(context.Background(), []byte(`{"source": "aws.events", "detail-type": "Scheduled Event", "detail": {}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []EventSource{EventSourceDirect, EventSourceSchedule}, sources)
+		assert.Empty(t, calls)
+	})
+
+	t.Run("no handler", func(t *testing.T) {
+		v := New(NewEventRouter().Handle(EventSourceSQS, func() error { return nil }).Serve)
+		_, err := v.Invoke(context.Background(), []byte(`{"Records": [{"eventSource": "aws:kinesis"}]}`))
+		assert.EqualError(t, err, "no handler is registered for kinesis events")
+	})
+}
@@ -0,0 +1,118 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var cloudWatchEventType = reflect.TypeOf(events.CloudWatchEvent{})
+
+// EventBridgeRouter dispatches EventBridge (and CloudWatch Events) events to the handler registered for the source
+// and detail type of the event, and unmarshals the event detail into the input parameter of the handler. Each route
+// has its own handler and middleware chain.
+//
+// An EventBridgeRouter is used as the handler of a Vesper instance, or for EventBridge events of an EventRouter:
+//
+//	r := vesper.NewEventBridgeRouter().
+//		Handle("users", "UserCreated", UserCreatedHandler).
+//		Handle("users", "UserDeleted", UserDeletedHandler, auditMiddleware).
+//		Handle("aws.events", "*", ScheduleHandler)
+//	vesper.New(r.Serve, vesper.CorrelationIDMiddleware()).Start()
+type EventBridgeRouter struct {
+	routes   []*eventBridgeRoute
+	fallback *eventBridgeRoute
+}
+
+type eventBridgeRoute struct {
+	source     string
+	detailType string
+	tIn        reflect.Type
+	handler    LambdaFunc
+}
+
+// NewEventBridgeRouter creates a new EventBridgeRouter with no routes
+func NewEventBridgeRouter() *EventBridgeRouter {
+	return &EventBridgeRouter{}
+}
+
+// Handle registers a handler and route-scoped middlewares for events with the source and detail type. Either may
+// be "*" to match any value, and events are dispatched to the first matching route in the order they were registered.
+//
+// The handler may have any signature accepted by New. If its input parameter is an events.CloudWatchEvent the event
+// is passed as is, otherwise the event detail is JSON unmarshaled into the input parameter.
+// Route middlewares receive the events.CloudWatchEvent as input, and the event is also available via
+// EventBridgeEventFromContext.
+func (r *EventBridgeRouter) Handle(source, detailType string, handler interface{}, middlewares ...Middleware) *EventBridgeRouter {
+	route := newEventBridgeRoute(handler, middlewares)
+	route.source = source
+	route.detailType = detailType
+	r.routes = append(r.routes, route)
+	return r
+}
+
+// Default registers a handler and middlewares for events which match no route. See Handle.
+func (r *EventBridgeRouter) Default(handler interface{}, middlewares ...Middleware) *EventBridgeRouter {
+	r.fallback = newEventBridgeRoute(handler, middlewares)
+	return r
+}
+
+// Serve dispatches the event to the matching route, and is the handler to give to New. An error is returned if no
+// route matches the event and there is no default handler.
+func (r *EventBridgeRouter) Serve(ctx context.Context, event events.CloudWatchEvent) (interface{}, error) {
+	if event.Source == "" && event.DetailType == "" {
+		return nil, errors.New("event bridge router expected an EventBridge event")
+	}
+
+	route := r.fallback
+	for _, rt := range r.routes {
+		if matchWildcard(rt.source, event.Source) && matchWildcard(rt.detailType, event.DetailType) {
+			route = rt
+			break
+		}
+	}
+	if route == nil {
+		return nil, fmt.Errorf("no handler is registered for %q events from %q", event.DetailType, event.Source)
+	}
+
+	ctx = context.WithValue(ctx, ctxKeyTIn, route.tIn)
+	ctx = context.WithValue(ctx, ctxKeyEventBridgeEvent, event)
+	return route.handler(ctx, event)
+}
+
+func newEventBridgeRoute(handler interface{}, middlewares []Middleware) *eventBridgeRoute {
+	return &eventBridgeRoute{
+		tIn:     handlerInputType(handler),
+		handler: buildChain(bindEventBridgeDetail(newTypedToUntypedWrapper(handler)), middlewares...),
+	}
+}
+
+func matchWildcard(pattern string, value string) bool {
+	return pattern == "*" || pattern == value
+}
+
+// bindEventBridgeDetail converts the events.CloudWatchEvent given to a route into the route handler input parameter type
+func bindEventBridgeDetail(next LambdaFunc) LambdaFunc {
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		event, ok := in.(events.CloudWatchEvent)
+		if !ok {
+			return next(ctx, in) // a route middleware has already converted the event
+		}
+		tIn, _ := TInFromContext(ctx)
+		if tIn == nil || tIn == cloudWatchEventType {
+			return next(ctx, in)
+		}
+		if len(event.Detail) == 0 {
+			return next(ctx, nil)
+		}
+		v, err := unmarshalToType(json.Unmarshal, tIn, event.Detail)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal detail of %q event to type of '%s': %w", event.DetailType, tIn.String(), err)
+		}
+		return next(ctx, v)
+	}
+}
@@ -0,0 +1,121 @@
+package vesper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBridgeRouter(t *testing.T) {
+	type userCreated struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	type userDeleted struct {
+		ID string `json:"id"`
+	}
+
+	var calls []interface{}
+	var routeMiddlewareInput interface{}
+	routeMiddleware := func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			routeMiddlewareInput = in
+			return next(ctx, in)
+		}
+	}
+	router := NewEventBridgeRouter().
+		Handle("users", "UserCreated", func(ctx context.Context, u userCreated) error {
+			event, ok := EventBridgeEventFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "UserCreated", event.DetailType)
+			calls = append(calls, u)
+			return nil
+		}, routeMiddleware).
+		Handle("users", "UserDeleted", func(u userDeleted) error {
+			calls = append(calls, u)
+			return nil
+		}).
+		Handle("aws.events", "*", func(ctx context.Context, e events.CloudWatchEvent) (string, error) {
+			calls = append(calls, e.DetailType)
+			return "scheduled", nil
+		})
+	v := New(router.Serve)
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected interface{}
+		response string
+		wantErr  string
+	}{
+		{
+			name:     "detail is unmarshaled into handler input",
+			payload:  `{"source": "users", "detail-type": "UserCreated", "detail": {"id": "1", "name": "bob"}}`,
+			expected: userCreated{ID: "1", Name: "bob"},
+			response: "null",
+		},
+		{
+			name:     "routes by detail type",
+			payload:  `{"source": "users", "detail-type": "UserDeleted", "detail": {"id": "1"}}`,
+			expected: userDeleted{ID: "1"},
+			response: "null",
+		},
+		{
+			name:     "wildcard detail type",
+			payload:  `{"source": "aws.events", "detail-type": "Scheduled Event", "detail": {}}`,
+			expected: "Scheduled Event",
+			response: `"scheduled"`,
+		},
+		{
+			name:    "no matching route",
+			payload: `{"source": "orders", "detail-type": "OrderCreated", "detail": {}}`,
+			wantErr: `no handler is registered for "OrderCreated" events from "orders"`,
+		},
+		{
+			name:    "invalid detail",
+			payload: `{"source": "users", "detail-type": "UserCreated", "detail": {"id": 1}}`,
+			wantErr: `could not unmarshal detail of "UserCreated" event to type of 'vesper.userCreated': json: cannot unmarshal number into Go struct field userCreated.id of type string`,
+		},
+		{
+			name:    "not an EventBridge event",
+			payload: `{"name": "bob"}`,
+			wantErr: "event bridge router expected an EventBridge event",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = nil
+			rsp, err := v.Invoke(context.Background(), []byte(tc.payload))
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				assert.Empty(t, calls)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []interface{}{tc.expected}, calls)
+			assert.Equal(t, tc.response, string(rsp))
+		})
+	}
+	assert.IsType(t, events.CloudWatchEvent{}, routeMiddlewareInput)
+
+	t.Run("default handler", func(t *testing.T) {
+		router := NewEventBridgeRouter().
+			Handle("users", "UserCreated", func() error { return nil }).
+			Default(func(ctx context.Context, e events.CloudWatchEvent) (string, error) {
+				return e.Source, nil
+			})
+		rsp, err := New(router.Serve).Invoke(context.Background(), []byte(`{"source": "orders", "detail-type": "OrderCreated"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"orders"`, string(rsp))
+	})
+
+	t.Run("event router", func(t *testing.T) {
+		calls = nil
+		v := New(NewEventRouter().Handle(EventSourceEventBridge, router.Serve).Serve)
+		_, err := v.Invoke(context.Background(), []byte(`{"source": "users", "detail-type": "UserDeleted", "detail": {"id": "2"}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{userDeleted{ID: "2"}}, calls)
+	})
+}
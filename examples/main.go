@@ -5,14 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 
 	"github.com/mefellows/vesper"
 )
 
 type User struct {
-	Username string
-	Password string
+	Username string `validate:"required,min=3"`
+	Password string `validate:"required,min=8"`
 }
 
 type Response struct {
@@ -37,11 +38,11 @@ func LoginHandler(ctx context.Context, u User) (Response, error) {
 func LoginHandler2(ctx context.Context, u User) (interface{}, error) {
 	log.Println("[userHandler] checking request type for warmup event or API call")
 	log.Println("[userHandler] Validating API request")
-	log.Println("[userHandler] Extracting correlation ID for event")
+	correlationID, _ := vesper.CorrelationIDFromContext(ctx)
+	log.Println("[userHandler] correlation ID for event:", correlationID)
 	log.Println("[userHandler] Authenticating request")
 	log.Println("[userHandler] Executing User API Call")
 	log.Println("[userHandler] validating output response body")
-	log.Println("[userHandler] setting correlation ID property")
 
 	return nil, nil
 }
@@ -60,30 +61,27 @@ var namedMiddleware = func(name string) vesper.Middleware {
 	}
 }
 
-var authMiddleware = func(f vesper.LambdaFunc) vesper.LambdaFunc {
+var authMiddleware = func(f vesper.TypedLambdaFunc[User, Response]) vesper.TypedLambdaFunc[User, Response] {
 	// one time scope setup area for middleware
 
-	return func(ctx context.Context, in interface{}) (interface{}, error) {
-		log.Println("[authMiddleware] START: ", in)
-		user := in.(User)
+	return func(ctx context.Context, user User) (Response, error) {
+		log.Println("[authMiddleware] START: ", user)
 		if user.Username == "" || user.Password == "" {
-			error := map[string]string{
-				"error": "unauthorised",
-			}
-			log.Println("[authMiddleware] user is unauthorised, short circuiting request: ", in)
-			return error, fmt.Errorf("user %v is unauthorised", user.Username)
+			log.Println("[authMiddleware] user is unauthorised, short circuiting request: ", user)
+			return Response{}, fmt.Errorf("user %v is unauthorised", user.Username)
 		}
 
-		res, err := f(ctx, in)
-		log.Printf("[authMiddleware] END: %+v \n", in)
+		res, err := f(ctx, user)
+		log.Printf("[authMiddleware] END: %+v \n", user)
 
 		return res, err
 	}
 }
 
 func main() {
-	vesper.Logger(log.New(os.Stdout, "", log.LstdFlags))
 	// m := vesper.New(LoginHandler2)
-	m := vesper.New(LoginHandler, namedMiddleware("requestValidationMiddleware"), namedMiddleware("correlationIdMiddleware"), authMiddleware)
+	m := vesper.NewTyped(LoginHandler, authMiddleware).
+		Use(vesper.CorrelationIDMiddleware(), vesper.ValidatorMiddleware(), namedMiddleware("loggingMiddleware")).
+		UseLogger(vesper.NewJSONLogger(os.Stdout, slog.LevelDebug))
 	m.Start()
 }
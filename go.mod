@@ -1,15 +1,31 @@
 module github.com/mefellows/vesper
 
-go 1.14
+go 1.21
+
+require (
+	github.com/aws/aws-lambda-go v1.47.0
+	github.com/stretchr/testify v1.8.4
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	go.opentelemetry.io/contrib/propagators/aws v1.24.0
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+	google.golang.org/protobuf v1.33.0
+	gopkg.in/yaml.v2 v2.2.8
+)
 
 require (
-	github.com/aws/aws-lambda-go v1.16.0
 	github.com/axw/gocov v1.0.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/mattn/goveralls v0.0.5 // indirect
 	github.com/mitchellh/gox v1.0.1 // indirect
 	github.com/modocache/gover v0.0.0-20171022184752-b58185e213c5 // indirect
-	github.com/stretchr/testify v1.5.1
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/tools v0.0.0-20200401192744-099440627f01 // indirect
-	gopkg.in/yaml.v2 v2.2.8 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
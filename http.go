@@ -0,0 +1,262 @@
+package vesper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HTTPEventSource identifies the AWS service an HTTP request was received from
+type HTTPEventSource string
+
+const (
+	// HTTPEventSourceAPIGateway is an API Gateway REST API proxy integration (payload format 1.0)
+	HTTPEventSourceAPIGateway HTTPEventSource = "apigateway"
+	// HTTPEventSourceAPIGatewayV2 is an API Gateway HTTP API integration (payload format 2.0)
+	HTTPEventSourceAPIGatewayV2 HTTPEventSource = "apigatewayv2"
+	// HTTPEventSourceALB is an Application Load Balancer target group
+	HTTPEventSourceALB HTTPEventSource = "alb"
+)
+
+// HTTPRequest is an HTTP request received from API Gateway or an ALB, independent of the event format.
+// It can be used as a handler input parameter, as it is unmarshaled from any of
+// events.APIGatewayProxyRequest, events.APIGatewayV2HTTPRequest or events.ALBTargetGroupRequest.
+type HTTPRequest struct {
+	Source                          HTTPEventSource
+	Method                          string
+	Path                            string
+	Headers                         map[string]string
+	MultiValueHeaders               map[string][]string
+	QueryStringParameters           map[string]string
+	MultiValueQueryStringParameters map[string][]string
+	PathParameters                  map[string]string
+	Body                            string
+	IsBase64Encoded                 bool
+	// Event is the original event the request was unmarshaled from
+	Event interface{}
+}
+
+// UnmarshalJSON detects the format of an HTTP event and converts it into an HTTPRequest.
+// Payloads which are not HTTP events are unmarshaled without error, but have no Source.
+func (r *HTTPRequest) UnmarshalJSON(b []byte) error {
+	var probe struct {
+		Version        string `json:"version"`
+		HTTPMethod     string `json:"httpMethod"`
+		RequestContext struct {
+			ELB *json.RawMessage `json:"elb"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return err
+	}
+
+	switch {
+	case probe.Version == "2.0":
+		evt := events.APIGatewayV2HTTPRequest{}
+		if err := json.Unmarshal(b, &evt); err != nil {
+			return err
+		}
+		headers := evt.Headers
+		if len(evt.Cookies) > 0 {
+			headers = copyHeaders(evt.Headers)
+			headers["cookie"] = strings.Join(evt.Cookies, "; ")
+		}
+		*r = HTTPRequest{
+			Source:                HTTPEventSourceAPIGatewayV2,
+			Method:                evt.RequestContext.HTTP.Method,
+			Path:                  evt.RawPath,
+			Headers:               headers,
+			QueryStringParameters: evt.QueryStringParameters,
+			PathParameters:        evt.PathParameters,
+			Body:                  evt.Body,
+			IsBase64Encoded:       evt.IsBase64Encoded,
+			Event:                 evt,
+		}
+	case probe.RequestContext.ELB != nil:
+		evt := events.ALBTargetGroupRequest{}
+		if err := json.Unmarshal(b, &evt); err != nil {
+			return err
+		}
+		*r = HTTPRequest{
+			Source:                          HTTPEventSourceALB,
+			Method:                          evt.HTTPMethod,
+			Path:                            evt.Path,
+			Headers:                         evt.Headers,
+			MultiValueHeaders:               evt.MultiValueHeaders,
+			QueryStringParameters:           evt.QueryStringParameters,
+			MultiValueQueryStringParameters: evt.MultiValueQueryStringParameters,
+			Body:                            evt.Body,
+			IsBase64Encoded:                 evt.IsBase64Encoded,
+			Event:                           evt,
+		}
+	case probe.HTTPMethod != "":
+		evt := events.APIGatewayProxyRequest{}
+		if err := json.Unmarshal(b, &evt); err != nil {
+			return err
+		}
+		*r = HTTPRequest{
+			Source:                          HTTPEventSourceAPIGateway,
+			Method:                          evt.HTTPMethod,
+			Path:                            evt.Path,
+			Headers:                         evt.Headers,
+			MultiValueHeaders:               evt.MultiValueHeaders,
+			QueryStringParameters:           evt.QueryStringParameters,
+			MultiValueQueryStringParameters: evt.MultiValueQueryStringParameters,
+			PathParameters:                  evt.PathParameters,
+			Body:                            evt.Body,
+			IsBase64Encoded:                 evt.IsBase64Encoded,
+			Event:                           evt,
+		}
+	default:
+		*r = HTTPRequest{}
+	}
+	return nil
+}
+
+// Header returns the first value of the named header, matched case-insensitively
+func (r HTTPRequest) Header(name string) string {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	for k, v := range r.MultiValueHeaders {
+		if strings.EqualFold(k, name) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// DecodedBody returns the request body, base64 decoding it if required
+func (r HTTPRequest) DecodedBody() ([]byte, error) {
+	if !r.IsBase64Encoded {
+		return []byte(r.Body), nil
+	}
+	b, err := base64.StdEncoding.DecodeString(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not base64 decode request body: %w", err)
+	}
+	return b, nil
+}
+
+// HTTPResponse is an HTTP response to return to API Gateway or an ALB.
+// It is marshaled into the response format expected by its Source.
+type HTTPResponse struct {
+	StatusCode        int
+	Headers           map[string]string
+	MultiValueHeaders map[string][]string
+	Body              string
+	IsBase64Encoded   bool
+	// Source selects the response format, and defaults to HTTPEventSourceAPIGateway
+	Source HTTPEventSource
+}
+
+// MarshalJSON converts the response into the response format expected by its Source
+func (r HTTPResponse) MarshalJSON() ([]byte, error) {
+	switch r.Source {
+	case HTTPEventSourceAPIGatewayV2:
+		// HTTP APIs do not support multi-value headers, so they are joined and cookies are returned separately
+		rsp := struct {
+			StatusCode      int               `json:"statusCode"`
+			Headers         map[string]string `json:"headers,omitempty"`
+			Cookies         []string          `json:"cookies,omitempty"`
+			Body            string            `json:"body"`
+			IsBase64Encoded bool              `json:"isBase64Encoded"`
+		}{
+			StatusCode:      r.StatusCode,
+			Headers:         map[string]string{},
+			Body:            r.Body,
+			IsBase64Encoded: r.IsBase64Encoded,
+		}
+		for k, v := range r.Headers {
+			rsp.Headers[k] = v
+		}
+		for k, v := range r.MultiValueHeaders {
+			if strings.EqualFold(k, "Set-Cookie") {
+				rsp.Cookies = append(rsp.Cookies, v...)
+				continue
+			}
+			rsp.Headers[k] = strings.Join(v, ",")
+		}
+		return json.Marshal(rsp)
+	case HTTPEventSourceALB:
+		return json.Marshal(events.ALBTargetGroupResponse{
+			StatusCode:        r.StatusCode,
+			StatusDescription: fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+			Headers:           r.Headers,
+			MultiValueHeaders: r.MultiValueHeaders,
+			Body:              r.Body,
+			IsBase64Encoded:   r.IsBase64Encoded,
+		})
+	default:
+		return json.Marshal(events.APIGatewayProxyResponse{
+			StatusCode:        r.StatusCode,
+			Headers:           r.Headers,
+			MultiValueHeaders: r.MultiValueHeaders,
+			Body:              r.Body,
+			IsBase64Encoded:   r.IsBase64Encoded,
+		})
+	}
+}
+
+// adaptHTTPResponse prepares a response to be returned for the given request.
+// ALB target groups use either single or multi-value headers, depending on whether multi-value headers
+// are enabled, and ignore the other kind so they are merged into the kind used by the request. Without multi-value
+// headers only the last Set-Cookie header is returned, as cookies cannot be combined into one header.
+func adaptHTTPResponse(req HTTPRequest, rsp HTTPResponse) HTTPResponse {
+	rsp.Source = req.Source
+	if req.Source != HTTPEventSourceALB {
+		return rsp
+	}
+	if len(req.MultiValueHeaders) > 0 && len(rsp.Headers) > 0 {
+		mvh := make(map[string][]string, len(rsp.MultiValueHeaders)+len(rsp.Headers))
+		for k, v := range rsp.MultiValueHeaders {
+			mvh[k] = v
+		}
+		for k, v := range rsp.Headers {
+			mvh[k] = append(mvh[k], v)
+		}
+		rsp.MultiValueHeaders = mvh
+		rsp.Headers = nil
+	}
+	if len(req.MultiValueHeaders) == 0 && len(rsp.MultiValueHeaders) > 0 {
+		headers := copyHeaders(rsp.Headers)
+		for k, v := range rsp.MultiValueHeaders {
+			if http.CanonicalHeaderKey(k) == "Set-Cookie" {
+				// cookies cannot be comma separated, so only the last is kept
+				headers[k] = v[len(v)-1]
+				continue
+			}
+			headers[k] = strings.Join(v, ",")
+		}
+		rsp.Headers = headers
+		rsp.MultiValueHeaders = nil
+	}
+	return rsp
+}
+
+// newJSONResponse creates an HTTPResponse with the given status code and v marshaled as a JSON body
+func newJSONResponse(statusCode int, v interface{}) (HTTPResponse, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+	return HTTPResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}, nil
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	c := make(map[string]string, len(headers))
+	for k, v := range headers {
+		c[k] = v
+	}
+	return c
+}
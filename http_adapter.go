@@ -0,0 +1,186 @@
+package vesper
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HTTPAdapter converts a standard http.Handler (e.g. http.ServeMux, chi or gorilla/mux) into a handler for New,
+// so existing HTTP services can be run on Lambda behind API Gateway (REST or HTTP APIs) or an ALB:
+//
+//	vesper.New(vesper.HTTPAdapter(mux), vesper.WarmupMiddleware).Start()
+//
+// Each event is converted into an *http.Request with the invocation context, and everything written to
+// the http.ResponseWriter is converted into the response for the event source.
+// Response bodies which are not text are base64 encoded. ALB target groups without multi-value headers enabled only
+// return the last Set-Cookie header.
+func HTTPAdapter(h http.Handler) func(context.Context, HTTPRequest) (HTTPResponse, error) {
+	return func(ctx context.Context, req HTTPRequest) (HTTPResponse, error) {
+		if req.Source == "" {
+			return HTTPResponse{}, errors.New("HTTP adapter expected an API Gateway or ALB HTTP event")
+		}
+		r, err := newNetHTTPRequest(ctx, req)
+		if err != nil {
+			return HTTPResponse{}, err
+		}
+		w := newResponseWriter()
+		h.ServeHTTP(w, r)
+		return adaptHTTPResponse(req, w.response()), nil
+	}
+}
+
+// newNetHTTPRequest converts an HTTPRequest into a net/http request
+func newNetHTTPRequest(ctx context.Context, req HTTPRequest) (*http.Request, error) {
+	body, err := req.DecodedBody()
+	if err != nil {
+		return nil, err
+	}
+
+	u := &url.URL{Path: req.Path}
+	if req.Source != HTTPEventSourceAPIGateway {
+		// HTTP APIs and ALBs give the raw path, REST APIs the decoded path
+		if u.Path, err = url.PathUnescape(req.Path); err != nil {
+			return nil, fmt.Errorf("could not decode request path: %w", err)
+		}
+		u.RawPath = req.Path
+	}
+	if evt, ok := req.Event.(events.APIGatewayV2HTTPRequest); ok {
+		u.RawQuery = evt.RawQueryString
+	} else {
+		// REST APIs give the decoded query string parameters, ALBs the parameters as they were sent
+		unescape := func(s string) (string, error) { return s, nil }
+		if req.Source == HTTPEventSourceALB {
+			unescape = url.QueryUnescape
+		}
+		query := url.Values{}
+		add := func(k, v string, onlyIfMissing bool) error {
+			if k, err = unescape(k); err != nil {
+				return fmt.Errorf("could not decode query string parameter: %w", err)
+			}
+			if v, err = unescape(v); err != nil {
+				return fmt.Errorf("could not decode query string parameter %s: %w", k, err)
+			}
+			if _, ok := query[k]; !ok || !onlyIfMissing {
+				query.Add(k, v)
+			}
+			return nil
+		}
+		for k, vs := range req.MultiValueQueryStringParameters {
+			for _, v := range vs {
+				if err := add(k, v, false); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for k, v := range req.QueryStringParameters {
+			if err := add(k, v, true); err != nil {
+				return nil, err
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %w", err)
+	}
+	for k, vs := range req.MultiValueHeaders {
+		for _, v := range vs {
+			r.Header.Add(k, v)
+		}
+	}
+	for k, v := range req.Headers {
+		if r.Header.Get(k) == "" {
+			r.Header.Set(k, v)
+		}
+	}
+	r.Host = r.Header.Get("Host")
+	r.RequestURI = u.RequestURI()
+	r.ContentLength = int64(len(body))
+
+	switch evt := req.Event.(type) {
+	case events.APIGatewayProxyRequest:
+		r.RemoteAddr = evt.RequestContext.Identity.SourceIP
+	case events.APIGatewayV2HTTPRequest:
+		r.RemoteAddr = evt.RequestContext.HTTP.SourceIP
+	}
+	return r, nil
+}
+
+// responseWriter buffers everything written by an http.Handler
+type responseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{header: http.Header{}}
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.statusCode != 0 {
+		return
+	}
+	w.statusCode = statusCode
+}
+
+// response converts what was written into an HTTPResponse
+func (w *responseWriter) response() HTTPResponse {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	if w.header.Get("Content-Type") == "" && w.body.Len() > 0 {
+		w.header.Set("Content-Type", http.DetectContentType(w.body.Bytes()))
+	}
+	rsp := HTTPResponse{
+		StatusCode:        w.statusCode,
+		MultiValueHeaders: map[string][]string(w.header),
+	}
+	if isTextContentType(w.header.Get("Content-Type")) {
+		rsp.Body = w.body.String()
+	} else {
+		rsp.Body = base64.StdEncoding.EncodeToString(w.body.Bytes())
+		rsp.IsBase64Encoded = true
+	}
+	return rsp
+}
+
+// isTextContentType reports whether a body of the given content type can be returned without base64 encoding
+func isTextContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/javascript", "application/x-www-form-urlencoded", "image/svg+xml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPAdapter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"method": r.Method,
+			"query":  r.URL.Query(),
+			"accept": r.Header.Values("Accept"),
+			"body":   string(body),
+		})
+	})
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path + " " + r.URL.String()))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	})
+
+	v := New(HTTPAdapter(mux))
+	invoke := func(t *testing.T, payload string) map[string]interface{} {
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(payload))
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rsp, &out))
+		return out
+	}
+
+	t.Run("API Gateway REST API", func(t *testing.T) {
+		rsp := invoke(t, `
+{
+  "httpMethod": "POST",
+  "path": "/echo",
+  "multiValueQueryStringParameters": {"tag": ["a", "b"]},
+  "multiValueHeaders": {"Accept": ["text/plain", "application/json"]},
+  "body": "aGVsbG8=",
+  "isBase64Encoded": true
+}
+`)
+		assert.Equal(t, float64(201), rsp["statusCode"])
+		assert.Equal(t, map[string]interface{}{"Content-Type": []interface{}{"application/json"}, "Set-Cookie": []interface{}{"a=1", "b=2"}}, rsp["multiValueHeaders"])
+		assert.JSONEq(t, `{"method": "POST", "query": {"tag": ["a", "b"]}, "accept": ["text/plain", "application/json"], "body": "hello"}`, rsp["body"].(string))
+	})
+
+	t.Run("API Gateway HTTP API", func(t *testing.T) {
+		rsp := invoke(t, `{"version": "2.0", "rawPath": "/echo", "rawQueryString": "tag=a", "requestContext": {"http": {"method": "GET"}}}`)
+		assert.Equal(t, float64(201), rsp["statusCode"])
+		assert.Equal(t, []interface{}{"a=1", "b=2"}, rsp["cookies"])
+		assert.Equal(t, map[string]interface{}{"Content-Type": "application/json"}, rsp["headers"])
+	})
+
+	t.Run("ALB without multi-value headers", func(t *testing.T) {
+		rsp := invoke(t, `{"httpMethod": "GET", "path": "/echo", "headers": {"accept": "*/*"}, "requestContext": {"elb": {"targetGroupArn": "arn"}}}`)
+		assert.Equal(t, "201 Created", rsp["statusDescription"])
+		assert.Equal(t, map[string]interface{}{"Content-Type": "application/json", "Set-Cookie": "b=2"}, rsp["headers"])
+		assert.Nil(t, rsp["multiValueHeaders"])
+	})
+
+	t.Run("ALB encoded query string", func(t *testing.T) {
+		for _, payload := range []string{
+			`{"httpMethod": "GET", "path": "/echo", "queryStringParameters": {"q": "a%20b", "tag%5B%5D": "x%26y"}, "requestContext": {"elb": {"targetGroupArn": "arn"}}}`,
+			`{"httpMethod": "GET", "path": "/echo", "multiValueQueryStringParameters": {"q": ["a%20b"], "tag%5B%5D": ["x%26y"]}, "requestContext": {"elb": {"targetGroupArn": "arn"}}}`,
+		} {
+			rsp := invoke(t, payload)
+			assert.JSONEq(t, `{"method": "GET", "query": {"q": ["a b"], "tag[]": ["x&y"]}, "accept": null, "body": ""}`, rsp["body"].(string), payload)
+		}
+	})
+
+	t.Run("encoded path", func(t *testing.T) {
+		for _, payload := range []string{
+			`{"httpMethod": "GET", "path": "/files/a b"}`,
+			`{"version": "2.0", "rawPath": "/files/a%20b", "requestContext": {"http": {"method": "GET"}}}`,
+			`{"httpMethod": "GET", "path": "/files/a%20b", "requestContext": {"elb": {"targetGroupArn": "arn"}}}`,
+		} {
+			rsp := invoke(t, payload)
+			assert.Equal(t, "/files/a b /files/a%20b", rsp["body"], payload)
+		}
+	})
+
+	t.Run("binary body", func(t *testing.T) {
+		rsp := invoke(t, `{"httpMethod": "GET", "path": "/image"}`)
+		assert.Equal(t, float64(200), rsp["statusCode"])
+		assert.Equal(t, true, rsp["isBase64Encoded"])
+		assert.Equal(t, "iVBORw0KGgo=", rsp["body"])
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rsp := invoke(t, `{"httpMethod": "GET", "path": "/missing"}`)
+		assert.Equal(t, float64(404), rsp["statusCode"])
+		assert.Nil(t, rsp["isBase64Encoded"])
+	})
+
+	t.Run("not an HTTP event", func(t *testing.T) {
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,113 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPError is an error which is returned to the caller as an HTTP response by HTTPErrorHandlerMiddleware
+type HTTPError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Code is an optional application specific error code
+	Code string
+	// Message is a human readable explanation of the error, safe to return to the caller
+	Message string
+	// Details is optional additional information, such as validation failures, which is JSON marshaled into the response
+	Details interface{}
+	// Err is the underlying cause of the error, which is never returned to the caller
+	Err error
+}
+
+// NewHTTPError creates an HTTPError with the given status code and message
+func NewHTTPError(statusCode int, message string) *HTTPError {
+	return &HTTPError{StatusCode: statusCode, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Message)
+	}
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPErrorMapper converts an error returned by a handler into the HTTP response returned to the caller
+type HTTPErrorMapper func(ctx context.Context, err error) HTTPResponse
+
+// problem is an RFC 7807 problem details body
+type problem struct {
+	Type    string      `json:"type"`
+	Title   string      `json:"title"`
+	Status  int         `json:"status"`
+	Detail  string      `json:"detail,omitempty"`
+	Code    string      `json:"code,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// ProblemHTTPErrorMapper is an HTTPErrorMapper which returns errors as RFC 7807 problem details
+// with a content type of application/problem+json.
+// An HTTPError anywhere in the error chain sets the status code, code, message and details of the response.
+// Any other error results in a 500 response, and its message is only included if exposeInternalErrors is true,
+// which should not be the case in production.
+func ProblemHTTPErrorMapper(exposeInternalErrors bool) HTTPErrorMapper {
+	return func(ctx context.Context, err error) HTTPResponse {
+		p := problem{Type: "about:blank", Status: http.StatusInternalServerError}
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			p.Status = httpErr.StatusCode
+			p.Code = httpErr.Code
+			p.Detail = httpErr.Message
+			p.Details = httpErr.Details
+		} else if exposeInternalErrors {
+			p.Detail = err.Error()
+		}
+		p.Title = http.StatusText(p.Status)
+
+		body, err := json.Marshal(p)
+		if err != nil {
+			body, _ = json.Marshal(problem{Type: "about:blank", Title: p.Title, Status: p.Status, Detail: p.Detail, Code: p.Code})
+		}
+		return HTTPResponse{
+			StatusCode: p.Status,
+			Headers:    map[string]string{"Content-Type": "application/problem+json"},
+			Body:       string(body),
+		}
+	}
+}
+
+// HTTPErrorHandlerMiddleware converts errors returned by the rest of the chain into HTTP responses using the given mapper,
+// so that API Gateway or the ALB returns them to the caller instead of a 502.
+// If mapper is nil, ProblemHTTPErrorMapper(false) is used.
+// The response is returned in the format of the HTTP event which was received. Errors of invocations by other events,
+// such as SQS messages, are returned unchanged so Lambda still retries or reports them.
+func HTTPErrorHandlerMiddleware(mapper HTTPErrorMapper) func(LambdaFunc) LambdaFunc {
+	if mapper == nil {
+		mapper = ProblemHTTPErrorMapper(false)
+	}
+	return NamedMiddleware("HTTPErrorHandlerMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			res, err := next(ctx, in)
+			if err == nil {
+				return res, nil
+			}
+			req, ok := httpRequestFromPayload(ctx)
+			if !ok {
+				// other events are retried or reported by Lambda on error, so the error is returned as is
+				return res, err
+			}
+			middlewareLogger(ctx, "HTTPErrorHandlerMiddleware").Error("handler returned error", "error", err)
+			return adaptHTTPResponse(req, mapper(ctx, err)), nil
+		}
+	})
+}
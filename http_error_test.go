@@ -0,0 +1,89 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("wrapped: %w", &HTTPError{StatusCode: http.StatusServiceUnavailable, Message: "try again later", Err: cause})
+	assert.EqualError(t, err, "wrapped: 503 Service Unavailable: try again later: connection refused")
+	assert.True(t, errors.Is(err, cause))
+}
+
+func TestHTTPErrorHandlerMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapper   HTTPErrorMapper
+		payload  string
+		err      error
+		expected string
+	}{
+		{
+			name:     "HTTPError",
+			payload:  `{"httpMethod": "GET", "path": "/users/1"}`,
+			err:      &HTTPError{StatusCode: http.StatusNotFound, Code: "USER_NOT_FOUND", Message: "user 1 does not exist", Details: map[string]string{"id": "1"}},
+			expected: `{"statusCode": 404, "headers": {"Content-Type": "application/problem+json"}, "multiValueHeaders": null, "body": "{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"user 1 does not exist\",\"code\":\"USER_NOT_FOUND\",\"details\":{\"id\":\"1\"}}"}`,
+		},
+		{
+			name:     "internal error is hidden",
+			payload:  `{"httpMethod": "GET", "path": "/users/1"}`,
+			err:      errors.New("database password is hunter2"),
+			expected: `{"statusCode": 500, "headers": {"Content-Type": "application/problem+json"}, "multiValueHeaders": null, "body": "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500}"}`,
+		},
+		{
+			name:     "internal error is exposed",
+			mapper:   ProblemHTTPErrorMapper(true),
+			payload:  `{"version": "2.0", "rawPath": "/users/1", "requestContext": {"http": {"method": "GET"}}}`,
+			err:      errors.New("something happened"),
+			expected: `{"statusCode": 500, "headers": {"Content-Type": "application/problem+json"}, "isBase64Encoded": false, "body": "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500,\"detail\":\"something happened\"}"}`,
+		},
+		{
+			name: "custom mapper",
+			mapper: func(ctx context.Context, err error) HTTPResponse {
+				return HTTPResponse{StatusCode: http.StatusTeapot, Body: err.Error()}
+			},
+			payload:  `{"httpMethod": "GET", "path": "/", "requestContext": {"elb": {"targetGroupArn": "arn"}}}`,
+			err:      errors.New("something happened"),
+			expected: `{"statusCode": 418, "statusDescription": "418 I'm a teapot", "headers": null, "multiValueHeaders": null, "body": "something happened", "isBase64Encoded": false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := func(ctx context.Context, req HTTPRequest) (HTTPResponse, error) {
+				return HTTPResponse{}, tt.err
+			}
+			v := New(h, HTTPErrorHandlerMiddleware(tt.mapper))
+			rsp, err := v.buildHandler().Invoke(context.Background(), []byte(tt.payload))
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(rsp))
+		})
+	}
+
+	t.Run("no error", func(t *testing.T) {
+		h := func(ctx context.Context) (string, error) {
+			return "ok", nil
+		}
+		v := New(h, HTTPErrorHandlerMiddleware(nil))
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"ok"`, string(rsp))
+	})
+
+	t.Run("errors of other events are returned", func(t *testing.T) {
+		h := func(ctx context.Context) error {
+			return errors.New("failed")
+		}
+		v := New(h, HTTPErrorHandlerMiddleware(nil))
+		for _, payload := range []string{`{"Records": [{"eventSource": "aws:sqs", "messageId": "1", "body": "{}"}]}`, `{"name": "bob"}`} {
+			_, err := v.buildHandler().Invoke(context.Background(), []byte(payload))
+			assert.EqualError(t, err, "failed")
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper/encoding"
+)
+
+// DefaultHTTPBodyUnmarshalers are the unmarshalers used by HTTPBodyParserMiddleware when none are given, by media type
+var DefaultHTTPBodyUnmarshalers = map[string]encoding.UnmarshalFunc{
+	"application/json":                  json.Unmarshal,
+	"application/x-www-form-urlencoded": encoding.UnmarshalForm,
+	"application/xml":                   xml.Unmarshal,
+	"text/xml":                          xml.Unmarshal,
+}
+
+var httpEventTypes = map[reflect.Type]bool{
+	httpRequestType: true,
+	reflect.TypeOf(events.APIGatewayProxyRequest{}):  true,
+	reflect.TypeOf(events.APIGatewayV2HTTPRequest{}): true,
+	reflect.TypeOf(events.ALBTargetGroupRequest{}):   true,
+}
+
+// HTTPBodyParserMiddleware is a middleware which unmarshals the body of an HTTP event from API Gateway or an ALB
+// into the handler input parameter type, choosing the unmarshaler by the Content-Type of the request.
+// Bodies are base64 decoded first if required, and requests without a Content-Type are unmarshaled as JSON.
+// If unmarshalers is nil, DefaultHTTPBodyUnmarshalers is used, which supports JSON, forms and XML.
+// Unless unmarshalers has its own, multipart/form-data bodies are unmarshaled with encoding.UnmarshalMultipartForm,
+// using the boundary parameter of the Content-Type.
+//
+// If there is no unmarshaler for the Content-Type an *HTTPError with a 415 status code is returned, and if the body
+// cannot be unmarshaled an *HTTPError with a 400 status code is returned.
+// Events which are not HTTP events, and handlers taking the HTTP event itself, are passed on as is.
+// The middleware can also be given to Router routes, to support bodies other than JSON.
+func HTTPBodyParserMiddleware(unmarshalers map[string]encoding.UnmarshalFunc) func(LambdaFunc) LambdaFunc {
+	if unmarshalers == nil {
+		unmarshalers = DefaultHTTPBodyUnmarshalers
+	}
+	return NamedMiddleware("HTTPBodyParserMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			tIn, ok := TInFromContext(ctx)
+			if !ok || tIn == nil || httpEventTypes[tIn] {
+				return next(ctx, in) // continue as there is no TIn to parse anyway.
+			}
+			req, ok := httpRequestFromPayload(ctx)
+			if !ok {
+				return next(ctx, in) // continue as the event is not an HTTP request
+			}
+
+			body, err := req.DecodedBody()
+			if err != nil {
+				return nil, &HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid request body", Err: err}
+			}
+			if len(body) == 0 {
+				return next(ctx, nil)
+			}
+
+			mediaType, params := "application/json", map[string]string{}
+			if contentType := req.Header("Content-Type"); contentType != "" {
+				if mediaType, params, err = mime.ParseMediaType(contentType); err != nil {
+					return nil, &HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid Content-Type", Err: err}
+				}
+			}
+			unmarshaler, ok := unmarshalers[strings.ToLower(mediaType)]
+			if !ok && strings.EqualFold(mediaType, "multipart/form-data") {
+				if params["boundary"] == "" {
+					return nil, &HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid Content-Type", Err: errors.New("multipart form boundary is missing")}
+				}
+				unmarshaler, ok = encoding.MultipartFormUnmarshaler(params["boundary"]), true
+			}
+			if !ok {
+				return nil, &HTTPError{StatusCode: http.StatusUnsupportedMediaType, Message: fmt.Sprintf("Content-Type '%s' is not supported", mediaType)}
+			}
+			nextIn, err := unmarshalToType(unmarshaler, tIn, body)
+			if err != nil {
+				middlewareLogger(ctx, "HTTPBodyParserMiddleware").Info("could not unmarshal request body", "type", tIn.String(), "contentType", mediaType, "error", err)
+				return nil, &HTTPError{
+					StatusCode: http.StatusBadRequest,
+					Message:    "request body could not be parsed",
+					Err:        fmt.Errorf("could not unmarshal request body to type of '%s': %w", tIn.String(), err),
+				}
+			}
+			return next(ctx, nextIn)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package vesper
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parsedLogin struct {
+	Username string `json:"username" form:"username" xml:"username"`
+	Password string `json:"password" form:"password" xml:"password"`
+}
+
+func TestHTTPBodyParserMiddleware(t *testing.T) {
+	newEvent := func(contentType string, body string, base64Encoded bool) []byte {
+		headers := map[string]string{}
+		if contentType != "" {
+			headers["Content-Type"] = contentType
+		}
+		b, _ := json.Marshal(map[string]interface{}{"httpMethod": "POST", "path": "/login", "headers": headers, "body": body, "isBase64Encoded": base64Encoded})
+		return b
+	}
+
+	tests := []struct {
+		name     string
+		payload  []byte
+		expected parsedLogin
+		status   int
+	}{
+		{name: "json", payload: newEvent("application/json; charset=utf-8", `{"username": "bob", "password": "hunter2"}`, false), expected: parsedLogin{Username: "bob", Password: "hunter2"}},
+		{name: "no content type", payload: newEvent("", `{"username": "bob"}`, false), expected: parsedLogin{Username: "bob"}},
+		{name: "form", payload: newEvent("application/x-www-form-urlencoded", "username=bob&password=hunter2", false), expected: parsedLogin{Username: "bob", Password: "hunter2"}},
+		{name: "base64 encoded form", payload: newEvent("application/x-www-form-urlencoded", base64.StdEncoding.EncodeToString([]byte("username=bob")), true), expected: parsedLogin{Username: "bob"}},
+		{name: "xml", payload: newEvent("text/xml", "<login><username>bob</username></login>", false), expected: parsedLogin{Username: "bob"}},
+		{name: "multipart", payload: newEvent("multipart/form-data; boundary=XYZ", "--XYZ\r\nContent-Disposition: form-data; name=\"username\"\r\n\r\nbob\r\n--XYZ--\r\n", false), expected: parsedLogin{Username: "bob"}},
+		{name: "multipart preamble", payload: newEvent("multipart/form-data; boundary=\"X Y\"", "preamble\r\n--X Y\r\nContent-Disposition: form-data; name=\"username\"\r\n\r\nbob\r\n--X Y--\r\n", false), expected: parsedLogin{Username: "bob"}},
+		{name: "multipart without boundary", payload: newEvent("multipart/form-data", "--XYZ\r\n\r\n--XYZ--\r\n", false), status: http.StatusBadRequest},
+		{name: "empty body", payload: newEvent("application/json", "", false)},
+		{name: "unsupported content type", payload: newEvent("image/png", "PNG", false), status: http.StatusUnsupportedMediaType},
+		{name: "invalid body", payload: newEvent("application/json", "{", false), status: http.StatusBadRequest},
+		{name: "invalid base64", payload: newEvent("application/json", "!", true), status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got parsedLogin
+			h := func(ctx context.Context, in parsedLogin) error {
+				got = in
+				return nil
+			}
+			v := New(h, HTTPBodyParserMiddleware(nil)).DisableAutoUnmarshal()
+			_, err := v.buildHandler().Invoke(context.Background(), tt.payload)
+			if tt.status != 0 {
+				var httpErr *HTTPError
+				assert.True(t, errors.As(err, &httpErr))
+				assert.Equal(t, tt.status, httpErr.StatusCode)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("not an HTTP event", func(t *testing.T) {
+		h := func(ctx context.Context, in parsedLogin) (string, error) {
+			return in.Username, nil
+		}
+		rsp, err := New(h, HTTPBodyParserMiddleware(nil)).buildHandler().Invoke(context.Background(), []byte(`{"username": "bob"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"bob"`, string(rsp))
+	})
+
+	t.Run("route", func(t *testing.T) {
+		h := func(ctx context.Context, in parsedLogin) (string, error) {
+			return in.Username, nil
+		}
+		r := NewRouter().Post("/login", h, HTTPBodyParserMiddleware(nil))
+		rsp, err := New(r.Serve).buildHandler().Invoke(context.Background(), newEvent("application/x-www-form-urlencoded", "username=bob", false))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"statusCode": 200, "headers": {"Content-Type": "application/json"}, "multiValueHeaders": null, "body": "\"bob\""}`, string(rsp))
+	})
+}
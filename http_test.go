@@ -0,0 +1,111 @@
+package vesper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPRequestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected HTTPRequest
+	}{
+		{
+			name:    "API Gateway REST API",
+			payload: `{"httpMethod": "POST", "path": "/users", "headers": {"Content-Type": "application/json"}, "body": "{}"}`,
+			expected: HTTPRequest{
+				Source:  HTTPEventSourceAPIGateway,
+				Method:  "POST",
+				Path:    "/users",
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    "{}",
+			},
+		},
+		{
+			name:    "API Gateway HTTP API",
+			payload: `{"version": "2.0", "rawPath": "/users", "cookies": ["a=1", "b=2"], "headers": {"accept": "*/*"}, "requestContext": {"http": {"method": "GET"}}}`,
+			expected: HTTPRequest{
+				Source:  HTTPEventSourceAPIGatewayV2,
+				Method:  "GET",
+				Path:    "/users",
+				Headers: map[string]string{"accept": "*/*", "cookie": "a=1; b=2"},
+			},
+		},
+		{
+			name:    "ALB",
+			payload: `{"httpMethod": "GET", "path": "/users", "multiValueHeaders": {"accept": ["*/*"]}, "requestContext": {"elb": {"targetGroupArn": "arn"}}}`,
+			expected: HTTPRequest{
+				Source:            HTTPEventSourceALB,
+				Method:            "GET",
+				Path:              "/users",
+				MultiValueHeaders: map[string][]string{"accept": {"*/*"}},
+			},
+		},
+		{
+			name:     "not an HTTP event",
+			payload:  `{"Event": {"source": "serverless-plugin-warmup"}}`,
+			expected: HTTPRequest{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req HTTPRequest
+			err := json.Unmarshal([]byte(tt.payload), &req)
+			assert.NoError(t, err)
+			req.Event = nil
+			assert.Equal(t, tt.expected, req)
+		})
+	}
+}
+
+func TestHTTPRequest(t *testing.T) {
+	req := HTTPRequest{
+		Headers:           map[string]string{"Content-Type": "application/json"},
+		MultiValueHeaders: map[string][]string{"Accept": {"text/plain", "*/*"}},
+		Body:              "aGVsbG8=",
+		IsBase64Encoded:   true,
+	}
+	assert.Equal(t, "application/json", req.Header("content-type"))
+	assert.Equal(t, "text/plain", req.Header("accept"))
+	assert.Equal(t, "", req.Header("x-missing"))
+	body, err := req.DecodedBody()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), body)
+}
+
+func TestHTTPResponseMarshalJSON(t *testing.T) {
+	rsp := HTTPResponse{
+		StatusCode:        201,
+		Headers:           map[string]string{"Content-Type": "application/json"},
+		MultiValueHeaders: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+		Body:              "{}",
+	}
+	tests := []struct {
+		source   HTTPEventSource
+		expected string
+	}{
+		{
+			source:   HTTPEventSourceAPIGateway,
+			expected: `{"statusCode": 201, "headers": {"Content-Type": "application/json"}, "multiValueHeaders": {"Set-Cookie": ["a=1", "b=2"]}, "body": "{}"}`,
+		},
+		{
+			source:   HTTPEventSourceAPIGatewayV2,
+			expected: `{"statusCode": 201, "headers": {"Content-Type": "application/json"}, "cookies": ["a=1", "b=2"], "body": "{}", "isBase64Encoded": false}`,
+		},
+		{
+			source:   HTTPEventSourceALB,
+			expected: `{"statusCode": 201, "statusDescription": "201 Created", "headers": {"Content-Type": "application/json"}, "multiValueHeaders": {"Set-Cookie": ["a=1", "b=2"]}, "body": "{}", "isBase64Encoded": false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.source), func(t *testing.T) {
+			rsp.Source = tt.source
+			b, err := json.Marshal(rsp)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(b))
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/mefellows/vesper/jsonschema"
+)
+
+// JSONSchemaValidatorMiddleware is a middleware which validates the original payload against the input schema
+// before it is parsed, and the handler response against the output schema. Either schema may be nil to skip
+// that validation. Schemas can be parsed from JSON with jsonschema.Parse, or generated from a type with jsonschema.Reflect.
+//
+// When used as route middleware of a Router the request body is validated instead of the HTTP event, and
+// when the response is an HTTPResponse its body is validated.
+//
+// If the input is invalid an *HTTPError with a 400 status code is returned, with the jsonschema.ValidationErrors
+// listing every failing path as its Details. If the response is invalid an error wrapping the
+// jsonschema.ValidationErrors is returned, which HTTPErrorHandlerMiddleware returns as a 500 response.
+func JSONSchemaValidatorMiddleware(input *jsonschema.Schema, output *jsonschema.Schema) func(LambdaFunc) LambdaFunc {
+	return NamedMiddleware("JSONSchemaValidatorMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			if err := validateInput(ctx, "JSONSchemaValidatorMiddleware", input); err != nil {
+				return nil, err
+			}
+			res, err := next(ctx, in)
+			if err != nil || output == nil {
+				return res, err
+			}
+			if err := validateOutput(output, res); err != nil {
+				middlewareLogger(ctx, "JSONSchemaValidatorMiddleware").Error("invalid response", "error", err)
+				return nil, err
+			}
+			return res, nil
+		}
+	})
+}
+
+var reflectedSchemas sync.Map
+
+// TInJSONSchemaValidatorMiddleware is a middleware which validates the original payload against a schema generated
+// from the handler input parameter type with jsonschema.Reflect, so constraints can be declared with struct tags:
+//
+//	type LoginRequest struct {
+//		Email    string `json:"email" jsonschema:"format=email"`
+//		Password string `json:"password" jsonschema:"minLength=8"`
+//	}
+//
+// Invalid input is handled in the same way as JSONSchemaValidatorMiddleware.
+// Handlers taking an HTTPRequest or no input parameter are not validated.
+func TInJSONSchemaValidatorMiddleware() func(LambdaFunc) LambdaFunc {
+	return NamedMiddleware("TInJSONSchemaValidatorMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			tIn, ok := TInFromContext(ctx)
+			if !ok || tIn == nil || tIn == httpRequestType {
+				return next(ctx, in) // continue as there is no TIn to validate against anyway.
+			}
+			schema, err := reflectedSchema(tIn)
+			if err != nil {
+				return nil, err
+			}
+			if err := validateInput(ctx, "TInJSONSchemaValidatorMiddleware", schema); err != nil {
+				return nil, err
+			}
+			return next(ctx, in)
+		}
+	})
+}
+
+// reflectedSchema generates the schema for a type, caching it as reflection is slow
+func reflectedSchema(t reflect.Type) (*jsonschema.Schema, error) {
+	if s, ok := reflectedSchemas.Load(t); ok {
+		return s.(*jsonschema.Schema), nil
+	}
+	s, err := jsonschema.Reflect(t)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate JSON schema for type of '%s': %w", t.String(), err)
+	}
+	reflectedSchemas.Store(t, s)
+	return s, nil
+}
+
+// validateInput validates the request body of a route, or the original payload, against the schema
+func validateInput(ctx context.Context, middleware string, schema *jsonschema.Schema) error {
+	if schema == nil {
+		return nil
+	}
+	var payload []byte
+	if req, ok := HTTPRequestFromContext(ctx); ok {
+		body, err := req.DecodedBody()
+		if err != nil {
+			return &HTTPError{StatusCode: http.StatusBadRequest, Message: "invalid request body", Err: err}
+		}
+		payload = body
+	} else if payload, ok = PayloadFromContext(ctx); !ok {
+		return fmt.Errorf("%s could not find the payload to validate", middleware)
+	}
+	if err := schema.ValidateJSON(payload); err != nil {
+		middlewareLogger(ctx, middleware).Info("invalid input", "error", err)
+		return &HTTPError{StatusCode: http.StatusBadRequest, Message: "input failed validation", Details: err, Err: err}
+	}
+	return nil
+}
+
+// validateOutput validates the JSON representation of a response, or the body of an HTTPResponse, against the schema
+func validateOutput(schema *jsonschema.Schema, res interface{}) error {
+	var b []byte
+	switch rsp := res.(type) {
+	case HTTPResponse:
+		b = []byte(rsp.Body)
+	case *HTTPResponse:
+		b = []byte(rsp.Body)
+	default:
+		var err error
+		if b, err = json.Marshal(res); err != nil {
+			return fmt.Errorf("could not marshal response for validation: %w", err)
+		}
+	}
+	if err := schema.ValidateJSON(b); err != nil {
+		return fmt.Errorf("response failed validation: %w", err)
+	}
+	return nil
+}
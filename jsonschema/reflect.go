@@ -0,0 +1,332 @@
+package jsonschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	rawMessageType      = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// Reflect generates a schema for the JSON representation of a Go type, as produced by encoding/json.
+//
+// Struct fields are named using the `json` struct tag, and are required unless they are pointers or are
+// tagged with omitempty. Constraints are added with the `jsonschema` struct tag, as a comma separated list of
+// keywords and values:
+//
+//	type LoginRequest struct {
+//		Email    string `json:"email" jsonschema:"format=email"`
+//		Password string `json:"password" jsonschema:"minLength=8,maxLength=64"`
+//		Role     string `json:"role,omitempty" jsonschema:"enum=admin|user"`
+//	}
+//
+// The supported keywords are title, description, format, pattern, enum (values separated by |), minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, minLength, maxLength, minItems, maxItems, uniqueItems,
+// minProperties and maxProperties, and required or optional to override whether the field is required.
+// Types with a custom JSON unmarshaler allow any value, except time.Time which is a date-time string.
+func Reflect(t reflect.Type) (*Schema, error) {
+	r := &reflector{building: map[reflect.Type]bool{}, referenced: map[reflect.Type]bool{}, root: t}
+	s, err := r.reflect(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.definitions) > 0 {
+		s.Definitions = r.definitions
+	}
+	return s, nil
+}
+
+// MustReflect is like Reflect but panics if the schema cannot be generated
+func MustReflect(t reflect.Type) *Schema {
+	s, err := Reflect(t)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+type reflector struct {
+	root        reflect.Type
+	building    map[reflect.Type]bool
+	referenced  map[reflect.Type]bool
+	definitions map[string]*Schema
+}
+
+func (r *reflector) reflect(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}, nil
+	case t == rawMessageType:
+		return &Schema{}, nil
+	case reflect.PtrTo(t).Implements(jsonUnmarshalerType):
+		return &Schema{}, nil
+	case reflect.PtrTo(t).Implements(textMarshalerType) && t.Kind() != reflect.Struct:
+		return &Schema{Type: Types{"string"}}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}, nil
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			// []byte is base64 encoded
+			return &Schema{Type: Types{"string"}}, nil
+		}
+		items, err := r.reflect(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		// nil slices are marshaled as null
+		s := &Schema{Type: Types{"array", "null"}, Items: items}
+		if t.Kind() == reflect.Array {
+			s.Type = Types{"array"}
+			s.MinItems, s.MaxItems = intPtr(t.Len()), intPtr(t.Len())
+		}
+		return s, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String && !reflect.PtrTo(t.Key()).Implements(textMarshalerType) &&
+			!isIntegerKind(t.Key().Kind()) {
+			return nil, fmt.Errorf("cannot generate JSON schema for map with key type '%s'", t.Key())
+		}
+		values, err := r.reflect(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Types{"object", "null"}, AdditionalProperties: values}, nil
+	case reflect.Struct:
+		return r.reflectStruct(t)
+	}
+	return nil, fmt.Errorf("cannot generate JSON schema for type '%s'", t)
+}
+
+// reflectStruct generates the schema for a struct, using a reference for recursive types
+func (r *reflector) reflectStruct(t reflect.Type) (*Schema, error) {
+	if r.building[t] {
+		r.referenced[t] = true
+		return &Schema{Ref: r.ref(t)}, nil
+	}
+	r.building[t] = true
+	defer delete(r.building, t)
+
+	s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+	if err := r.addFields(s, t); err != nil {
+		return nil, err
+	}
+	if !r.referenced[t] || t == r.root {
+		return s, nil
+	}
+	if r.definitions == nil {
+		r.definitions = map[string]*Schema{}
+	}
+	r.definitions[definitionName(t)] = s
+	return &Schema{Ref: r.ref(t)}, nil
+}
+
+func (r *reflector) ref(t reflect.Type) string {
+	if t == r.root {
+		return "#"
+	}
+	return "#/definitions/" + definitionName(t)
+}
+
+func definitionName(t reflect.Type) string {
+	return strings.ReplaceAll(t.String(), "/", "~1")
+}
+
+func (r *reflector) addFields(s *Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonTag := f.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, opts := parseTag(jsonTag)
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// the fields of embedded structs are promoted
+				if err := r.addFields(s, ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop, err := r.reflect(f.Type)
+		if err != nil {
+			return fmt.Errorf("field '%s': %w", f.Name, err)
+		}
+		required := f.Type.Kind() != reflect.Ptr && !opts["omitempty"]
+		if tag, ok := f.Tag.Lookup("jsonschema"); ok {
+			if prop.Ref != "" {
+				// constraints cannot be added alongside $ref
+				prop = &Schema{AllOf: []*Schema{prop}}
+			}
+			if required, err = applyTag(prop, tag, required); err != nil {
+				return fmt.Errorf("field '%s': %w", f.Name, err)
+			}
+		}
+		s.Properties[name] = prop
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := map[string]bool{}
+	for _, o := range parts[1:] {
+		opts[o] = true
+	}
+	return parts[0], opts
+}
+
+// applyTag adds the constraints in a jsonschema struct tag to the schema, returning whether the field is required
+func applyTag(s *Schema, tag string, required bool) (bool, error) {
+	for _, kv := range strings.Split(tag, ",") {
+		if kv == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(kv, "=")
+		switch key {
+		case "required":
+			required = true
+			continue
+		case "optional":
+			required = false
+			continue
+		case "uniqueItems":
+			s.UniqueItems = true
+			continue
+		}
+		if !hasValue {
+			return required, fmt.Errorf("jsonschema tag keyword '%s' requires a value", key)
+		}
+
+		var err error
+		switch key {
+		case "title":
+			s.Title = value
+		case "description":
+			s.Description = value
+		case "format":
+			s.Format = value
+		case "pattern":
+			if _, err = compilePattern(value); err == nil {
+				s.Pattern = value
+			}
+		case "enum":
+			for _, e := range strings.Split(value, "|") {
+				s.Enum = append(s.Enum, enumValue(s, e))
+			}
+		case "minimum":
+			s.Minimum, err = floatPtr(value)
+		case "maximum":
+			s.Maximum, err = floatPtr(value)
+		case "exclusiveMinimum":
+			s.ExclusiveMinimum, err = floatPtr(value)
+		case "exclusiveMaximum":
+			s.ExclusiveMaximum, err = floatPtr(value)
+		case "multipleOf":
+			s.MultipleOf, err = floatPtr(value)
+		case "minLength":
+			s.MinLength, err = parseIntPtr(value)
+		case "maxLength":
+			s.MaxLength, err = parseIntPtr(value)
+		case "minItems":
+			s.MinItems, err = parseIntPtr(value)
+		case "maxItems":
+			s.MaxItems, err = parseIntPtr(value)
+		case "minProperties":
+			s.MinProperties, err = parseIntPtr(value)
+		case "maxProperties":
+			s.MaxProperties, err = parseIntPtr(value)
+		default:
+			return required, fmt.Errorf("unsupported jsonschema tag keyword '%s'", key)
+		}
+		if err != nil {
+			return required, fmt.Errorf("invalid value for jsonschema tag keyword '%s': %w", key, err)
+		}
+	}
+	return required, nil
+}
+
+// enumValue converts an enum value from a struct tag into the type of the schema
+func enumValue(s *Schema, value string) interface{} {
+	for _, t := range s.Type {
+		switch t {
+		case "integer", "number":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func parseIntPtr(s string) (*int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func floatPtr(s string) (*float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,86 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tree struct {
+	Name     string  `json:"name"`
+	Children []*tree `json:"children,omitempty"`
+}
+
+func TestReflect(t *testing.T) {
+	type audit struct {
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	type user struct {
+		audit
+		Email    string            `json:"email" jsonschema:"format=email"`
+		Password string            `json:"password" jsonschema:"minLength=8,maxLength=64"`
+		Role     string            `json:"role,omitempty" jsonschema:"enum=admin|user"`
+		Age      *int              `json:"age" jsonschema:"minimum=18"`
+		Labels   map[string]string `json:"labels,omitempty"`
+		Avatar   []byte            `json:"avatar,omitempty"`
+		Internal string            `json:"-"`
+		secret   string
+	}
+
+	s, err := Reflect(reflect.TypeOf(user{}))
+	assert.NoError(t, err)
+	b, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"createdAt": {"type": "string", "format": "date-time"},
+			"email": {"type": "string", "format": "email"},
+			"password": {"type": "string", "minLength": 8, "maxLength": 64},
+			"role": {"type": "string", "enum": ["admin", "user"]},
+			"age": {"type": "integer", "minimum": 18},
+			"labels": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+			"avatar": {"type": "string"}
+		},
+		"required": ["createdAt", "email", "password"]
+	}`, string(b))
+
+	err = s.ValidateJSON([]byte(`{"createdAt": "yesterday", "email": "bob", "password": "hunter2", "role": "owner", "age": 12}`))
+	assert.Equal(t, ValidationErrors{
+		{Path: "/age", Message: "must be greater than or equal to 18"},
+		{Path: "/createdAt", Message: "must be a valid date-time"},
+		{Path: "/email", Message: "must be a valid email"},
+		{Path: "/password", Message: "must be at least 8 characters long"},
+		{Path: "/role", Message: `must be one of "admin", "user"`},
+	}, err)
+}
+
+func TestReflectRecursive(t *testing.T) {
+	s, err := Reflect(reflect.TypeOf(tree{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "#", s.Properties["children"].Items.Ref)
+
+	assert.NoError(t, s.ValidateJSON([]byte(`{"name": "a", "children": [{"name": "b", "children": [{"name": "c"}]}]}`)))
+	assert.Equal(t, ValidationErrors{{Path: "/children/0/children/0/name", Message: "is required"}},
+		s.ValidateJSON([]byte(`{"name": "a", "children": [{"name": "b", "children": [{}]}]}`)))
+
+	type forest struct {
+		Trees []tree `json:"trees"`
+	}
+	s, err = Reflect(reflect.TypeOf(forest{}))
+	assert.NoError(t, err)
+	assert.Contains(t, s.Definitions, "jsonschema.tree")
+	assert.Equal(t, ValidationErrors{{Path: "/trees/0/children/0/name", Message: "is required"}},
+		s.ValidateJSON([]byte(`{"trees": [{"name": "b", "children": [{}]}]}`)))
+}
+
+func TestReflectInvalidTag(t *testing.T) {
+	type invalid struct {
+		Name string `json:"name" jsonschema:"minLength=a"`
+	}
+	_, err := Reflect(reflect.TypeOf(invalid{}))
+	assert.EqualError(t, err, `field 'Name': invalid value for jsonschema tag keyword 'minLength': strconv.Atoi: parsing "a": invalid syntax`)
+}
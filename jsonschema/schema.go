@@ -0,0 +1,314 @@
+// Package jsonschema implements validation of JSON documents against a JSON Schema (every draft-07 validation
+// keyword, with local references only), and generation of schemas from Go types.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Schema is a JSON Schema. It can be parsed from JSON with Parse, built by hand, or generated from a Go type with Reflect.
+// Only local references (e.g. "#/definitions/user") are supported by $ref.
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Definitions map[string]*Schema `json:"definitions,omitempty"`
+	Defs        map[string]*Schema `json:"$defs,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+
+	Type  Types            `json:"type,omitempty"`
+	Enum  []interface{}    `json:"enum,omitempty"`
+	Const *json.RawMessage `json:"const,omitempty"`
+	AllOf []*Schema        `json:"allOf,omitempty"`
+	AnyOf []*Schema        `json:"anyOf,omitempty"`
+	OneOf []*Schema        `json:"oneOf,omitempty"`
+	Not   *Schema          `json:"not,omitempty"`
+	If    *Schema          `json:"if,omitempty"`
+	Then  *Schema          `json:"then,omitempty"`
+	Else  *Schema          `json:"else,omitempty"`
+
+	// numbers
+	MultipleOf       *float64 `json:"multipleOf,omitempty"`
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+
+	// strings
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	Format    string `json:"format,omitempty"`
+
+	// arrays
+	Items *Schema `json:"-"`
+	// TupleItems is the list form of items, where each item is validated against the schema at the same position
+	// and any further items against AdditionalItems
+	TupleItems      []*Schema `json:"-"`
+	AdditionalItems *Schema   `json:"additionalItems,omitempty"`
+	Contains        *Schema   `json:"contains,omitempty"`
+	MinItems        *int      `json:"minItems,omitempty"`
+	MaxItems        *int      `json:"maxItems,omitempty"`
+	UniqueItems     bool      `json:"uniqueItems,omitempty"`
+
+	// objects
+	Properties           map[string]*Schema     `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema     `json:"patternProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *Schema                `json:"additionalProperties,omitempty"`
+	PropertyNames        *Schema                `json:"propertyNames,omitempty"`
+	Dependencies         map[string]*Dependency `json:"dependencies,omitempty"`
+	MinProperties        *int                   `json:"minProperties,omitempty"`
+	MaxProperties        *int                   `json:"maxProperties,omitempty"`
+
+	// boolean is set for the boolean schemas true (anything is valid) and false (nothing is valid)
+	boolean *bool
+}
+
+// Bool returns the boolean schema true, which any value is valid against, or false, which no value is valid against
+func Bool(b bool) *Schema {
+	return &Schema{boolean: &b}
+}
+
+// Parse parses a JSON Schema document, compiling any patterns it contains and resolving any references
+func Parse(b []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("could not parse JSON schema: %w", err)
+	}
+	if err := s.compile(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustParse is like Parse but panics if the schema cannot be parsed.
+// It simplifies the initialisation of global variables holding schemas.
+func MustParse(b []byte) *Schema {
+	s, err := Parse(b)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+type schemaAlias Schema
+
+// schemaJSON is the JSON form of a schema object, where items is either a schema or a list of schemas
+type schemaJSON struct {
+	*schemaAlias
+	Items json.RawMessage `json:"items,omitempty"`
+}
+
+// UnmarshalJSON supports boolean schemas as well as schema objects
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	trimmed := bytes.TrimSpace(b)
+	if bytes.Equal(trimmed, []byte("true")) || bytes.Equal(trimmed, []byte("false")) {
+		v := trimmed[0] == 't'
+		*s = Schema{boolean: &v}
+		return nil
+	}
+	j := schemaJSON{schemaAlias: (*schemaAlias)(s)}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	items := bytes.TrimSpace(j.Items)
+	switch {
+	case len(items) == 0:
+		return nil
+	case items[0] == '[':
+		return json.Unmarshal(items, &s.TupleItems)
+	default:
+		s.Items = &Schema{}
+		return json.Unmarshal(items, s.Items)
+	}
+}
+
+// MarshalJSON supports boolean schemas as well as schema objects
+func (s Schema) MarshalJSON() ([]byte, error) {
+	if s.boolean != nil {
+		return json.Marshal(*s.boolean)
+	}
+	j := schemaJSON{schemaAlias: (*schemaAlias)(&s)}
+	var err error
+	switch {
+	case s.TupleItems != nil:
+		j.Items, err = json.Marshal(s.TupleItems)
+	case s.Items != nil:
+		j.Items, err = json.Marshal(s.Items)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// Dependency is a value of the dependencies keyword, which applies to an object when it has the property the
+// dependency is for. It is either the list of properties the object must also have, or a schema it must be valid against.
+type Dependency struct {
+	Required []string
+	Schema   *Schema
+}
+
+// UnmarshalJSON accepts either a list of property names or a schema
+func (d *Dependency) UnmarshalJSON(b []byte) error {
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, &d.Required)
+	}
+	d.Schema = &Schema{}
+	return json.Unmarshal(b, d.Schema)
+}
+
+// MarshalJSON returns the list of property names, or the schema
+func (d Dependency) MarshalJSON() ([]byte, error) {
+	if d.Schema != nil {
+		return json.Marshal(d.Schema)
+	}
+	return json.Marshal(d.Required)
+}
+
+// compile checks the patterns of the schema and its subschemas can be compiled, and their references resolved
+func (s *Schema) compile(root *Schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		if _, err := root.dereference(s); err != nil {
+			return err
+		}
+	}
+	if s.Pattern != "" {
+		if _, err := compilePattern(s.Pattern); err != nil {
+			return err
+		}
+	}
+	for pattern := range s.PatternProperties {
+		if _, err := compilePattern(pattern); err != nil {
+			return err
+		}
+	}
+	for _, sub := range s.subschemas() {
+		if err := sub.compile(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) subschemas() []*Schema {
+	subs := []*Schema{s.Not, s.If, s.Then, s.Else, s.Items, s.AdditionalItems, s.Contains, s.AdditionalProperties, s.PropertyNames}
+	subs = append(subs, s.AllOf...)
+	subs = append(subs, s.AnyOf...)
+	subs = append(subs, s.OneOf...)
+	subs = append(subs, s.TupleItems...)
+	for _, m := range []map[string]*Schema{s.Definitions, s.Defs, s.Properties, s.PatternProperties} {
+		for _, sub := range m {
+			subs = append(subs, sub)
+		}
+	}
+	for _, d := range s.Dependencies {
+		if d != nil {
+			subs = append(subs, d.Schema)
+		}
+	}
+	return subs
+}
+
+// dereference follows the $ref of s, and of the schemas it refers to, until a schema which is not a reference.
+// References which lead back to a schema already followed are an error, as validating against them never ends.
+func (s *Schema) dereference(ref *Schema) (*Schema, error) {
+	followed := map[*Schema]bool{}
+	for ref.Ref != "" {
+		if followed[ref] {
+			return nil, fmt.Errorf("$ref '%s' is a cycle of references", ref.Ref)
+		}
+		followed[ref] = true
+		next, err := s.resolve(ref.Ref)
+		if err != nil {
+			return nil, err
+		}
+		ref = next
+	}
+	return ref, nil
+}
+
+// resolve finds the schema referenced by a local $ref
+func (s *Schema) resolve(ref string) (*Schema, error) {
+	if ref == "#" {
+		return s, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref '%s', only local references are supported", ref)
+	}
+	current := s
+	parts := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+	for i := 0; i < len(parts); i++ {
+		var m map[string]*Schema
+		switch parts[i] {
+		case "definitions":
+			m = current.Definitions
+		case "$defs":
+			m = current.Defs
+		case "properties":
+			m = current.Properties
+		default:
+			return nil, fmt.Errorf("unsupported $ref '%s'", ref)
+		}
+		i++
+		if i >= len(parts) {
+			return nil, fmt.Errorf("unsupported $ref '%s'", ref)
+		}
+		name := strings.ReplaceAll(strings.ReplaceAll(parts[i], "~1", "/"), "~0", "~")
+		next, ok := m[name]
+		if !ok {
+			return nil, fmt.Errorf("could not resolve $ref '%s'", ref)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+var patterns sync.Map
+
+// compilePattern compiles a pattern, caching the result as schemas are immutable once built
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s' in JSON schema: %w", pattern, err)
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// Types is the value of the type keyword, which may be a single type or a list of types
+type Types []string
+
+// UnmarshalJSON accepts either a single type or a list of types
+func (t *Types) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings: %w", err)
+	}
+	*t = list
+	return nil
+}
+
+// MarshalJSON returns a single type as a string, and multiple types as a list
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
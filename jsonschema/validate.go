@@ -0,0 +1,489 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError describes a value which is not valid against its schema
+type ValidationError struct {
+	// Path is the JSON pointer (RFC 6901) of the invalid value, e.g. "/users/0/email", or "/" for the document itself
+	Path string `json:"path"`
+	// Message describes why the value is invalid
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors is every failure found when validating a document
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateJSON validates a JSON document against the schema.
+// If the document is not valid, the returned error is ValidationErrors listing every failure.
+func (s *Schema) ValidateJSON(b []byte) error {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return ValidationErrors{{Path: "/", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	return s.Validate(v)
+}
+
+// Validate validates a value decoded from JSON (i.e. made up of map[string]interface{}, []interface{}, string,
+// float64 or json.Number, bool and nil) against the schema.
+// If the value is not valid, the returned error is ValidationErrors listing every failure.
+func (s *Schema) Validate(v interface{}) error {
+	vr := &validator{root: s, refs: map[*Schema]*Schema{}}
+	vr.validate(s, v, "")
+	if len(vr.errs) > 0 {
+		return vr.errs
+	}
+	return nil
+}
+
+type validator struct {
+	root *Schema
+	// refs caches the schema each reference resolves to, for the validation of a document
+	refs map[*Schema]*Schema
+	errs ValidationErrors
+}
+
+func (vr *validator) fail(path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "/"
+	}
+	vr.errs = append(vr.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// valid reports whether v is valid against s without recording any failures
+func (vr *validator) valid(s *Schema, v interface{}, path string) bool {
+	sub := &validator{root: vr.root, refs: vr.refs}
+	sub.validate(s, v, path)
+	return len(sub.errs) == 0
+}
+
+func (vr *validator) validate(s *Schema, v interface{}, path string) {
+	if s == nil {
+		return
+	}
+	if s.boolean != nil {
+		if !*s.boolean {
+			vr.fail(path, "no value is allowed")
+		}
+		return
+	}
+	if s.Ref != "" {
+		ref, ok := vr.refs[s]
+		if !ok {
+			var err error
+			if ref, err = vr.root.dereference(s); err != nil {
+				vr.fail(path, "%v", err)
+				return
+			}
+			vr.refs[s] = ref
+		}
+		// as in draft-07, other keywords are ignored alongside $ref
+		vr.validate(ref, v, path)
+		return
+	}
+
+	if len(s.Type) > 0 && !matchesAnyType(s.Type, v) {
+		vr.fail(path, "must be of type %s but is %s", strings.Join(s.Type, " or "), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if equal(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vr.fail(path, "must be one of %s", formatValues(s.Enum))
+		}
+	}
+	if s.Const != nil {
+		var c interface{}
+		if err := json.Unmarshal(*s.Const, &c); err == nil && !equal(c, v) {
+			vr.fail(path, "must be %s", string(*s.Const))
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		vr.validate(sub, v, path)
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, sub := range s.AnyOf {
+			if vr.valid(sub, v, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			vr.fail(path, "must match at least one schema in anyOf")
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if vr.valid(sub, v, path) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			vr.fail(path, "must match exactly one schema in oneOf but matches %d", matched)
+		}
+	}
+	if s.Not != nil && vr.valid(s.Not, v, path) {
+		vr.fail(path, "must not match the schema in not")
+	}
+	if s.If != nil {
+		if vr.valid(s.If, v, path) {
+			vr.validate(s.Then, v, path)
+		} else {
+			vr.validate(s.Else, v, path)
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		vr.validateString(s, val, path)
+	case []interface{}:
+		vr.validateArray(s, val, path)
+	case map[string]interface{}:
+		vr.validateObject(s, val, path)
+	default:
+		if n, ok := toFloat(v); ok {
+			vr.validateNumber(s, n, path)
+		}
+	}
+}
+
+func (vr *validator) validateNumber(s *Schema, n float64, path string) {
+	if s.Minimum != nil && n < *s.Minimum {
+		vr.fail(path, "must be greater than or equal to %v", *s.Minimum)
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		vr.fail(path, "must be less than or equal to %v", *s.Maximum)
+	}
+	if s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum {
+		vr.fail(path, "must be greater than %v", *s.ExclusiveMinimum)
+	}
+	if s.ExclusiveMaximum != nil && n >= *s.ExclusiveMaximum {
+		vr.fail(path, "must be less than %v", *s.ExclusiveMaximum)
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		q := n / *s.MultipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			vr.fail(path, "must be a multiple of %v", *s.MultipleOf)
+		}
+	}
+}
+
+func (vr *validator) validateString(s *Schema, str string, path string) {
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		vr.fail(path, "must be at least %d characters long", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		vr.fail(path, "must be at most %d characters long", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		re, err := compilePattern(s.Pattern)
+		if err != nil {
+			vr.fail(path, "%v", err)
+		} else if !re.MatchString(str) {
+			vr.fail(path, "must match pattern '%s'", s.Pattern)
+		}
+	}
+	if s.Format != "" {
+		if check, ok := formats[s.Format]; ok && !check(str) {
+			vr.fail(path, "must be a valid %s", s.Format)
+		}
+	}
+}
+
+func (vr *validator) validateArray(s *Schema, items []interface{}, path string) {
+	if s.MinItems != nil && len(items) < *s.MinItems {
+		vr.fail(path, "must have at least %d items", *s.MinItems)
+	}
+	if s.MaxItems != nil && len(items) > *s.MaxItems {
+		vr.fail(path, "must have at most %d items", *s.MaxItems)
+	}
+	if s.UniqueItems {
+	unique:
+		for i := 0; i < len(items); i++ {
+			for j := i + 1; j < len(items); j++ {
+				if equal(items[i], items[j]) {
+					vr.fail(path, "must have unique items but items %d and %d are equal", i, j)
+					break unique
+				}
+			}
+		}
+	}
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s/%d", path, i)
+		switch {
+		case s.TupleItems == nil:
+			vr.validate(s.Items, item, itemPath)
+		case i < len(s.TupleItems):
+			vr.validate(s.TupleItems[i], item, itemPath)
+		case s.AdditionalItems != nil && s.AdditionalItems.boolean != nil && !*s.AdditionalItems.boolean:
+			vr.fail(itemPath, "is not an allowed item")
+		default:
+			vr.validate(s.AdditionalItems, item, itemPath)
+		}
+	}
+	if s.Contains != nil {
+		contains := false
+		for i, item := range items {
+			if vr.valid(s.Contains, item, fmt.Sprintf("%s/%d", path, i)) {
+				contains = true
+				break
+			}
+		}
+		if !contains {
+			vr.fail(path, "must contain an item matching the schema in contains")
+		}
+	}
+}
+
+func (vr *validator) validateObject(s *Schema, obj map[string]interface{}, path string) {
+	if s.MinProperties != nil && len(obj) < *s.MinProperties {
+		vr.fail(path, "must have at least %d properties", *s.MinProperties)
+	}
+	if s.MaxProperties != nil && len(obj) > *s.MaxProperties {
+		vr.fail(path, "must have at most %d properties", *s.MaxProperties)
+	}
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			vr.fail(pointer(path, name), "is required")
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	patterns := make([]string, 0, len(s.PatternProperties))
+	for pattern := range s.PatternProperties {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, k := range keys {
+		if d := s.Dependencies[k]; d != nil {
+			for _, name := range d.Required {
+				if _, ok := obj[name]; !ok {
+					vr.fail(pointer(path, name), "is required when %s is present", k)
+				}
+			}
+			vr.validate(d.Schema, obj, path)
+		}
+	}
+	for _, k := range keys {
+		if s.PropertyNames != nil && !vr.valid(s.PropertyNames, k, pointer(path, k)) {
+			vr.fail(pointer(path, k), "is not an allowed property name")
+		}
+		sub, matched := s.Properties[k]
+		if matched {
+			vr.validate(sub, obj[k], pointer(path, k))
+		}
+		for _, pattern := range patterns {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				vr.fail(path, "%v", err)
+				continue
+			}
+			if re.MatchString(k) {
+				matched = true
+				vr.validate(s.PatternProperties[pattern], obj[k], pointer(path, k))
+			}
+		}
+		if matched {
+			continue
+		}
+		if s.AdditionalProperties != nil {
+			if s.AdditionalProperties.boolean != nil && !*s.AdditionalProperties.boolean {
+				vr.fail(pointer(path, k), "is not an allowed property")
+				continue
+			}
+			vr.validate(s.AdditionalProperties, obj[k], pointer(path, k))
+		}
+	}
+}
+
+// pointer appends a property name to a JSON pointer, escaping it as per RFC 6901
+func pointer(path string, name string) string {
+	return path + "/" + strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func matchesAnyType(types Types, v interface{}) bool {
+	for _, t := range types {
+		if matchesType(t, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(t string, v interface{}) bool {
+	switch t {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "integer":
+		n, ok := toFloat(v)
+		return ok && n == math.Trunc(n)
+	}
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if n, ok := toFloat(v); ok {
+		if n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// equal compares JSON values, treating numbers of any representation as equal if they have the same value
+func equal(a interface{}, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		return ok && af == bf
+	}
+	switch av := a.(type) {
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if other, ok := bv[k]; !ok || !equal(v, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func formatValues(values []interface{}) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		b, _ := json.Marshal(v)
+		strs[i] = string(b)
+	}
+	return strings.Join(strs, ", ")
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// formats are the checks for the supported values of the format keyword. Unknown formats are ignored.
+var formats = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	},
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	},
+	"uuid": uuidPattern.MatchString,
+	"ipv4": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	},
+	"ipv6": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	},
+}
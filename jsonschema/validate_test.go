@@ -0,0 +1,223 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateJSON(t *testing.T) {
+	schema := MustParse([]byte(`{
+		"type": "object",
+		"definitions": {
+			"address": {
+				"type": "object",
+				"properties": {"postcode": {"type": "string", "pattern": "^[0-9]{4}$"}},
+				"required": ["postcode"]
+			}
+		},
+		"properties": {
+			"email": {"type": "string", "format": "email"},
+			"name": {"type": "string", "minLength": 2, "maxLength": 5},
+			"age": {"type": "integer", "minimum": 18, "exclusiveMaximum": 150},
+			"role": {"enum": ["admin", "user"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 3, "uniqueItems": true},
+			"address": {"$ref": "#/definitions/address"},
+			"id": {"oneOf": [{"type": "string", "format": "uuid"}, {"type": "integer"}]}
+		},
+		"required": ["email", "name"],
+		"additionalProperties": false
+	}`))
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected ValidationErrors
+	}{
+		{
+			name:    "valid",
+			payload: `{"email": "bob@example.com", "name": "Bob", "age": 30, "role": "admin", "tags": ["a", "b"], "address": {"postcode": "3000"}, "id": 1}`,
+		},
+		{
+			name:    "every failure is reported",
+			payload: `{"name": "Bobbity", "age": 17.5, "role": "owner", "tags": ["a", "a", 1, "c"], "address": {"postcode": "30"}, "id": "x", "extra": true}`,
+			expected: ValidationErrors{
+				{Path: "/email", Message: "is required"},
+				{Path: "/address/postcode", Message: "must match pattern '^[0-9]{4}$'"},
+				{Path: "/age", Message: "must be of type integer but is number"},
+				{Path: "/extra", Message: "is not an allowed property"},
+				{Path: "/id", Message: "must match exactly one schema in oneOf but matches 0"},
+				{Path: "/name", Message: "must be at most 5 characters long"},
+				{Path: "/role", Message: `must be one of "admin", "user"`},
+				{Path: "/tags", Message: "must have at most 3 items"},
+				{Path: "/tags", Message: "must have unique items but items 0 and 1 are equal"},
+				{Path: "/tags/2", Message: "must be of type string but is integer"},
+			},
+		},
+		{
+			name:     "wrong type",
+			payload:  `[]`,
+			expected: ValidationErrors{{Path: "/", Message: "must be of type object but is array"}},
+		},
+		{
+			name:     "invalid JSON",
+			payload:  `{`,
+			expected: ValidationErrors{{Path: "/", Message: "invalid JSON: unexpected EOF"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateJSON([]byte(tt.payload))
+			if tt.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.expected, err)
+		})
+	}
+}
+
+func TestValidateApplicators(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		payload  string
+		expected ValidationErrors
+	}{
+		{
+			name:     "patternProperties",
+			schema:   `{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`,
+			payload:  `{"x-id": 1, "y": "a"}`,
+			expected: ValidationErrors{{Path: "/x-id", Message: "must be of type string but is integer"}, {Path: "/y", Message: "is not an allowed property"}},
+		},
+		{
+			name:     "if then else",
+			schema:   `{"if": {"properties": {"kind": {"const": "user"}}}, "then": {"required": ["email"]}, "else": {"required": ["name"]}}`,
+			payload:  `{"kind": "user"}`,
+			expected: ValidationErrors{{Path: "/email", Message: "is required"}},
+		},
+		{
+			name:     "else",
+			schema:   `{"if": {"properties": {"kind": {"const": "user"}}}, "then": {"required": ["email"]}, "else": {"required": ["name"]}}`,
+			payload:  `{"kind": "group"}`,
+			expected: ValidationErrors{{Path: "/name", Message: "is required"}},
+		},
+		{
+			name:     "propertyNames",
+			schema:   `{"propertyNames": {"maxLength": 3}}`,
+			payload:  `{"abc": 1, "abcd": 2}`,
+			expected: ValidationErrors{{Path: "/abcd", Message: "is not an allowed property name"}},
+		},
+		{
+			name:     "dependencies",
+			schema:   `{"dependencies": {"card": ["address"], "discount": {"required": ["code"]}}}`,
+			payload:  `{"card": "1234", "discount": 10}`,
+			expected: ValidationErrors{{Path: "/address", Message: "is required when card is present"}, {Path: "/code", Message: "is required"}},
+		},
+		{
+			name:     "contains",
+			schema:   `{"contains": {"type": "integer"}}`,
+			payload:  `["a", "b"]`,
+			expected: ValidationErrors{{Path: "/", Message: "must contain an item matching the schema in contains"}},
+		},
+		{
+			name:     "tuple items",
+			schema:   `{"items": [{"type": "string"}, {"type": "integer"}], "additionalItems": false}`,
+			payload:  `["a", "b", 3]`,
+			expected: ValidationErrors{{Path: "/1", Message: "must be of type integer but is string"}, {Path: "/2", Message: "is not an allowed item"}},
+		},
+		{
+			name:     "additional items",
+			schema:   `{"items": [{"type": "string"}], "additionalItems": {"type": "integer"}}`,
+			payload:  `["a", 1, "c"]`,
+			expected: ValidationErrors{{Path: "/2", Message: "must be of type integer but is string"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MustParse([]byte(tt.schema)).ValidateJSON([]byte(tt.payload))
+			assert.Equal(t, tt.expected, err)
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	err := ValidationErrors{{Path: "/", Message: "must be of type object but is array"}, {Path: "/name", Message: "is required"}}
+	assert.EqualError(t, err, "/: must be of type object but is array; /name: is required")
+}
+
+func TestValidateFormats(t *testing.T) {
+	tests := map[string]map[string]bool{
+		"email":     {"bob@example.com": true, "Bob <bob@example.com>": false, "bob": false},
+		"date-time": {"2020-01-02T03:04:05Z": true, "2020-01-02": false},
+		"date":      {"2020-01-02": true, "2020-13-02": false},
+		"uri":       {"https://example.com/a": true, "example.com": false},
+		"uuid":      {"3f1f6f0e-1c2b-4c9e-9a0e-2d7f3b8c1a2b": true, "3f1f6f0e": false},
+		"ipv4":      {"10.0.0.1": true, "::1": false},
+		"ipv6":      {"::1": true, "10.0.0.1": false},
+	}
+	for format, values := range tests {
+		s := &Schema{Format: format}
+		for value, valid := range values {
+			err := s.Validate(value)
+			assert.Equal(t, valid, err == nil, "%s %s", format, value)
+		}
+	}
+}
+
+func TestBooleanSchema(t *testing.T) {
+	s := MustParse([]byte(`{"properties": {"anything": true, "nothing": false}}`))
+	assert.NoError(t, s.ValidateJSON([]byte(`{"anything": 1}`)))
+	assert.Equal(t, ValidationErrors{{Path: "/nothing", Message: "no value is allowed"}}, s.ValidateJSON([]byte(`{"nothing": 1}`)))
+
+	b, err := s.MarshalJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"properties": {"anything": true, "nothing": false}}`, string(b))
+}
+
+func TestParseInvalidPattern(t *testing.T) {
+	_, err := Parse([]byte(`{"items": {"pattern": "("}}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"patternProperties": {"(": {}}}`))
+	assert.Error(t, err)
+}
+
+func TestParseUnresolvableRef(t *testing.T) {
+	_, err := Parse([]byte(`{"properties": {"user": {"$ref": "#/definitions/user"}}}`))
+	assert.EqualError(t, err, "could not resolve $ref '#/definitions/user'")
+	_, err = Parse([]byte(`{"$ref": "https://example.com/user.json"}`))
+	assert.EqualError(t, err, "unsupported $ref 'https://example.com/user.json', only local references are supported")
+}
+
+func TestRefCycles(t *testing.T) {
+	for _, schema := range []string{
+		`{"$ref": "#"}`,
+		`{"definitions": {"a": {"$ref": "#/definitions/a"}}}`,
+		`{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"$ref": "#/definitions/a"}}}`,
+	} {
+		_, err := Parse([]byte(schema))
+		assert.ErrorContains(t, err, "is a cycle of references", schema)
+	}
+
+	// schemas built by hand are not parsed, so the cycle fails validation instead
+	s := &Schema{Ref: "#"}
+	assert.EqualError(t, s.ValidateJSON([]byte(`{}`)), "/: $ref '#' is a cycle of references")
+
+	// recursion through a property is not a cycle, as it ends with the document
+	s, err := Parse([]byte(`{"type": "object", "properties": {"child": {"$ref": "#"}}, "required": ["name"]}`))
+	assert.NoError(t, err)
+	assert.NoError(t, s.ValidateJSON([]byte(`{"name": "a", "child": {"name": "b", "child": {"name": "c"}}}`)))
+	assert.EqualError(t, s.ValidateJSON([]byte(`{"name": "a", "child": {"child": {}}}`)), "/child/name: is required; /child/child/name: is required")
+}
+
+func TestMarshalItems(t *testing.T) {
+	for _, schema := range []string{
+		`{"items": {"type": "string"}}`,
+		`{"items": [{"type": "string"}, true], "additionalItems": false}`,
+		`{"dependencies": {"a": ["b"], "c": {"required": ["d"]}}}`,
+	} {
+		b, err := MustParse([]byte(schema)).MarshalJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, schema, string(b))
+	}
+}
@@ -0,0 +1,99 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/mefellows/vesper/jsonschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchemaValidatorMiddleware(t *testing.T) {
+	type login struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	input := jsonschema.MustParse([]byte(`{
+		"type": "object",
+		"properties": {"email": {"type": "string", "format": "email"}, "password": {"type": "string", "minLength": 8}},
+		"required": ["email", "password"]
+	}`))
+	output := jsonschema.MustParse([]byte(`{"type": "object", "required": ["token"]}`))
+
+	t.Run("valid", func(t *testing.T) {
+		h := func(ctx context.Context, in login) (map[string]string, error) {
+			return map[string]string{"token": "abc"}, nil
+		}
+		v := New(h, JSONSchemaValidatorMiddleware(input, output))
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{"email": "bob@example.com", "password": "password123"}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"token": "abc"}`, string(rsp))
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		called := false
+		h := func(ctx context.Context, in login) (map[string]string, error) {
+			called = true
+			return nil, nil
+		}
+		v := New(h, JSONSchemaValidatorMiddleware(input, output))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"email": "bob", "password": "short"}`))
+		var httpErr *HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+		assert.Equal(t, jsonschema.ValidationErrors{
+			{Path: "/email", Message: "must be a valid email"},
+			{Path: "/password", Message: "must be at least 8 characters long"},
+		}, httpErr.Details)
+		assert.False(t, called)
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		h := func(ctx context.Context, in login) (map[string]string, error) {
+			return map[string]string{}, nil
+		}
+		v := New(h, JSONSchemaValidatorMiddleware(input, output))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"email": "bob@example.com", "password": "password123"}`))
+		assert.EqualError(t, err, "response failed validation: /token: is required")
+		var errs jsonschema.ValidationErrors
+		assert.True(t, errors.As(err, &errs))
+	})
+
+	t.Run("route request body", func(t *testing.T) {
+		h := func(ctx context.Context, in login) (string, error) {
+			return "ok", nil
+		}
+		r := NewRouter().Post("/login", h, JSONSchemaValidatorMiddleware(input, nil))
+		v := New(r.Serve, HTTPErrorHandlerMiddleware(nil))
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{"httpMethod": "POST", "path": "/login", "body": "{\"email\": \"bob@example.com\"}"}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"statusCode": 400,
+			"headers": {"Content-Type": "application/problem+json"},
+			"multiValueHeaders": null,
+			"body": "{\"type\":\"about:blank\",\"title\":\"Bad Request\",\"status\":400,\"detail\":\"input failed validation\",\"details\":[{\"path\":\"/password\",\"message\":\"is required\"}]}"
+		}`, string(rsp))
+	})
+}
+
+func TestTInJSONSchemaValidatorMiddleware(t *testing.T) {
+	type login struct {
+		Email    string `json:"email" jsonschema:"format=email"`
+		Password string `json:"password" jsonschema:"minLength=8"`
+	}
+	h := func(ctx context.Context, in login) (string, error) {
+		return in.Email, nil
+	}
+	v := New(h, TInJSONSchemaValidatorMiddleware())
+
+	rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{"email": "bob@example.com", "password": "password123"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `"bob@example.com"`, string(rsp))
+
+	_, err = v.buildHandler().Invoke(context.Background(), []byte(`{"password": "password123"}`))
+	var httpErr *HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, jsonschema.ValidationErrors{{Path: "/email", Message: "is required"}}, httpErr.Details)
+}
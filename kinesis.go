@@ -0,0 +1,129 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper/encoding"
+)
+
+// KinesisParserMiddleware transforms Kinesis event records into the handler input parameter type using the given unmarshaler.
+// The record data is base64 decoded before being passed to the unmarshaler.
+// The handler input parameter must be a slice.
+func KinesisParserMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) LambdaFunc {
+	validateTIn := func(tIn reflect.Type) error {
+		return validateRecordsTIn("KinesisParserMiddleware", "Kinesis", tIn, reflect.TypeOf(events.KinesisEvent{}))
+	}
+
+	unmarshalKinesisEvent := func(in interface{}) (events.KinesisEvent, error) {
+		evt := events.KinesisEvent{}
+		if err := unmarshalEvent("Kinesis", in, &evt); err != nil {
+			return events.KinesisEvent{}, err
+		}
+		return evt, nil
+	}
+
+	unmarshalRecords := func(tIn reflect.Type, evt events.KinesisEvent) (reflect.Value, error) {
+		tIns := reflect.MakeSlice(tIn, 0, len(evt.Records))
+		for _, r := range evt.Records {
+			data, err := unmarshalToType(unmarshaler, tIn.Elem(), r.Kinesis.Data)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("could not unmarshal Kinesis record data for sequence number %s: %w", r.Kinesis.SequenceNumber, err)
+			}
+			tIns = reflect.Append(tIns, reflect.ValueOf(data))
+		}
+		return tIns, nil
+	}
+
+	return NamedMiddleware("KinesisParserMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			if unmarshaler == nil {
+				return nil, errors.New("no unmarshaler was provided")
+			}
+			tIn, ok := TInFromContext(ctx)
+			if !ok {
+				return next(ctx, in) // continue as there is no TIn to parse anyway.
+			}
+			if err := validateTIn(tIn); err != nil {
+				return nil, err
+			}
+			evt, err := unmarshalKinesisEvent(in)
+			if err != nil {
+				return nil, err
+			}
+			tIns, err := unmarshalRecords(tIn, evt)
+			if err != nil {
+				return nil, err
+			}
+			return next(ctx, tIns.Interface())
+		}
+	})
+}
+
+// JSONKinesisParserMiddleware transforms Kinesis event records into the handler input parameter type using a JSON unmarshaler.
+// The handler input parameter must be a slice.
+func JSONKinesisParserMiddleware() func(LambdaFunc) LambdaFunc {
+	return KinesisParserMiddleware(json.Unmarshal)
+}
+
+// KinesisRecordHandlerMiddleware calls the handler once per Kinesis event record, unmarshaling the record data into the
+// handler input parameter type using the given unmarshaler. Records that fail to unmarshal, or for which the handler
+// returns an error, are reported by sequence number in a BatchResponse.
+// The handler input parameter is a single record, and the handler response is ignored.
+// The Kinesis record being processed can be retrieved with KinesisRecordFromContext.
+func KinesisRecordHandlerMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) LambdaFunc {
+	return ConcurrentKinesisRecordHandlerMiddleware(unmarshaler, 1)
+}
+
+// JSONKinesisRecordHandlerMiddleware calls the handler once per Kinesis event record using a JSON unmarshaler.
+// See KinesisRecordHandlerMiddleware.
+func JSONKinesisRecordHandlerMiddleware() func(LambdaFunc) LambdaFunc {
+	return KinesisRecordHandlerMiddleware(json.Unmarshal)
+}
+
+// ConcurrentKinesisRecordHandlerMiddleware is the same as KinesisRecordHandlerMiddleware, but processes up to concurrency
+// records at the same time. Records sharing a partition key are processed in order, and once one of them fails the
+// remaining records with that partition key are reported as failures without being processed.
+func ConcurrentKinesisRecordHandlerMiddleware(unmarshaler encoding.UnmarshalFunc, concurrency int) func(LambdaFunc) LambdaFunc {
+	validateTIn := func(tIn reflect.Type) error {
+		if tIn == reflect.TypeOf(events.KinesisEvent{}) {
+			return errors.New("KinesisRecordHandlerMiddleware middleware should not be used if input parameter is events.KinesisEvent")
+		}
+		return nil
+	}
+
+	parse := func(in interface{}) ([]batchRecord, error) {
+		if unmarshaler == nil {
+			return nil, errors.New("no unmarshaler was provided")
+		}
+		evt := events.KinesisEvent{}
+		if err := unmarshalEvent("Kinesis", in, &evt); err != nil {
+			return nil, err
+		}
+		records := make([]batchRecord, 0, len(evt.Records))
+		for _, r := range evt.Records {
+			r := r
+			records = append(records, batchRecord{
+				id:    r.Kinesis.SequenceNumber,
+				group: r.Kinesis.PartitionKey,
+				withContext: func(ctx context.Context) context.Context {
+					return context.WithValue(ctx, ctxKeyKinesisRecord, r)
+				},
+				decode: func(tIn reflect.Type) (interface{}, error) {
+					data, err := unmarshalToType(unmarshaler, tIn, r.Kinesis.Data)
+					if err != nil {
+						return nil, fmt.Errorf("could not unmarshal Kinesis record data for sequence number %s: %w", r.Kinesis.SequenceNumber, err)
+					}
+					return data, nil
+				},
+			})
+		}
+		return records, nil
+	}
+
+	return recordHandlerMiddleware("KinesisRecordHandlerMiddleware", concurrency, validateTIn, parse)
+}
@@ -0,0 +1,151 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKinesisParser(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	t.Run("no unmarshaler", func(t *testing.T) {
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := KinesisParserMiddleware(nil)(nextFunc)
+		_, err := middleware(context.Background(), []byte("{}"))
+		assert.Error(t, err)
+	})
+
+	t.Run("no TIn", func(t *testing.T) {
+		called := false
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			called = true
+			return nil, nil
+		}
+		middleware := KinesisParserMiddleware(json.Unmarshal)(nextFunc)
+		payload := []byte("{}")
+		ctx := context.WithValue(context.Background(), ctxKeyPayload, payload)
+		_, err := middleware(ctx, payload)
+		assert.NoError(t, err)
+		assert.True(t, called)
+	})
+
+	t.Run("invalid TIn", func(t *testing.T) {
+		tests := []struct {
+			name string
+			tIn  reflect.Type
+		}{
+			{name: "TIn is not a slice", tIn: reflect.TypeOf(user{})},
+			{name: "TIn is of type events.KinesisEvent", tIn: reflect.TypeOf(events.KinesisEvent{})},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+					t.Errorf("unexpected call to next func")
+					return nil, nil
+				}
+				middleware := KinesisParserMiddleware(json.Unmarshal)(nextFunc)
+				payload := []byte("{}")
+				ctx := context.WithValue(context.Background(), ctxKeyTIn, tt.tIn)
+				_, err := middleware(ctx, payload)
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("record data cannot be unmarshalled", func(t *testing.T) {
+		// "bm90IGEgdXNlcg==" is "not a user"
+		request := `{"Records": [{"kinesis": {"sequenceNumber": "1", "data": "bm90IGEgdXNlcg=="}}]}`
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := KinesisParserMiddleware(json.Unmarshal)(nextFunc)
+		payload := []byte(request)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf([]user{}))
+		_, err := middleware(ctx, payload)
+		assert.Error(t, err)
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		// data fields are base64 encoded {"name": "myuser", "age": 20} and {"name": "another user", "age": 18}
+		request := `
+{
+  "Records": [
+    {
+      "kinesis": {
+        "partitionKey": "1",
+        "sequenceNumber": "49590338271490256608559692538361571095921575989136588898",
+        "data": "eyJuYW1lIjogIm15dXNlciIsICJhZ2UiOiAyMH0="
+      }
+    },
+    {
+      "kinesis": {
+        "partitionKey": "2",
+        "sequenceNumber": "49590338271490256608559692540925702759324208523137515618",
+        "data": "eyJuYW1lIjogImFub3RoZXIgdXNlciIsICJhZ2UiOiAxOH0="
+      }
+    }
+  ]
+}
+`
+		called := false
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			called = true
+			assert.Equal(t, []user{{Name: "myuser", Age: 20}, {Name: "another user", Age: 18}}, in)
+			return nil, nil
+		}
+		middleware := JSONKinesisParserMiddleware()(nextFunc)
+		payload := []byte(request)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf([]user{}))
+		_, err := middleware(ctx, payload)
+		assert.NoError(t, err)
+		assert.True(t, called)
+	})
+}
+
+func TestKinesisRecordHandler(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	// data fields are base64 encoded {"name": "myuser", "age": 20}, "not a user" and {"name": "another user", "age": 18}
+	request := `
+{
+  "Records": [
+    {"kinesis": {"partitionKey": "1", "sequenceNumber": "1", "data": "eyJuYW1lIjogIm15dXNlciIsICJhZ2UiOiAyMH0="}},
+    {"kinesis": {"partitionKey": "2", "sequenceNumber": "2", "data": "bm90IGEgdXNlcg=="}},
+    {"kinesis": {"partitionKey": "3", "sequenceNumber": "3", "data": "eyJuYW1lIjogImFub3RoZXIgdXNlciIsICJhZ2UiOiAxOH0="}}
+  ]
+}
+`
+	var mu sync.Mutex
+	var handled []user
+	h := func(ctx context.Context, u user) error {
+		_, ok := KinesisRecordFromContext(ctx)
+		assert.True(t, ok)
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, u)
+		return nil
+	}
+	v := New(h).
+		DisableAutoUnmarshal().
+		Use(ConcurrentKinesisRecordHandlerMiddleware(json.Unmarshal, 3))
+	rsp, err := v.buildHandler().Invoke(context.Background(), []byte(request))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"batchItemFailures": [{"itemIdentifier": "2"}]}`, string(rsp))
+	assert.ElementsMatch(t, []user{{Name: "myuser", Age: 20}, {Name: "another user", Age: 18}}, handled)
+}
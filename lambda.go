@@ -5,20 +5,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+
+	"github.com/mefellows/vesper/encoding"
 )
 
 // This is the raw Lambda handler interface AWS needs to run a Lambda function
-type lambdaHandler func(context.Context, []byte) (interface{}, error)
+type lambdaHandler struct {
+	handler func(context.Context, []byte) (interface{}, error)
+	// marshal serializes the response, and defaults to json.Marshal
+	marshal encoding.MarshalFunc
+}
 
 // Invoke calls the handler, and serializes the response.
 // If the underlying handler returned an error, or an error occurs during serialization, error is returned.
 func (handler lambdaHandler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
-	response, err := handler(ctx, payload)
+	response, err := handler.handler(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
 
-	responseBytes, err := json.Marshal(response)
+	marshal := handler.marshal
+	if marshal == nil {
+		marshal = json.Marshal
+	}
+	responseBytes, err := marshal(response)
 	if err != nil {
 		return nil, err
 	}
@@ -27,9 +37,9 @@ func (handler lambdaHandler) Invoke(ctx context.Context, payload []byte) ([]byte
 }
 
 func errorHandler(e error) lambdaHandler {
-	return func(ctx context.Context, event []byte) (interface{}, error) {
+	return lambdaHandler{handler: func(ctx context.Context, event []byte) (interface{}, error) {
 		return nil, e
-	}
+	}}
 }
 
 func validateHandlerFunc(handlerInterface interface{}) error {
@@ -88,6 +98,18 @@ func validateReturns(handler reflect.Type) error {
 	return nil
 }
 
+// handlerInputType returns the type of the input parameter of the handler, or nil if it has none
+func handlerInputType(handler interface{}) reflect.Type {
+	t, err := handlerType(handler)
+	if err != nil {
+		return nil
+	}
+	if (!handlerTakesContext(t) && t.NumIn() == 1) || t.NumIn() == 2 {
+		return t.In(t.NumIn() - 1)
+	}
+	return nil
+}
+
 func handlerTakesContext(handlerType reflect.Type) bool {
 	if handlerType.NumIn() > 0 {
 		contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
@@ -0,0 +1,132 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// Run runs the handler as the runtime of a function, taking invocations from the Runtime API at the address, e.g.
+// a Server or the value of AWS_LAMBDA_RUNTIME_API, and posting their responses, until the context is cancelled.
+// Each invocation is given a LambdaContext and deadline as in Lambda, and a returned error or panic is posted as an
+// ErrorResponse.
+func Run(ctx context.Context, runtimeAPI string, h lambda.Handler) error {
+	client := &http.Client{}
+	endpoint := "http://" + runtimeAPI + runtimePrefix
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"next", nil)
+		if err != nil {
+			return fmt.Errorf("could not create next invocation request: %w", err)
+		}
+		rsp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("could not get next invocation: %w", err)
+		}
+		payload, err := io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("could not read next invocation: %w", err)
+		}
+		if rsp.StatusCode != http.StatusOK {
+			return fmt.Errorf("could not get next invocation: %s", rsp.Status)
+		}
+
+		requestID := rsp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+		res, errorType := invoke(ctx, h, rsp.Header, payload)
+		path := endpoint + requestID + "/response"
+		if errorType != "" {
+			path = endpoint + requestID + "/error"
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(res))
+		if err != nil {
+			return fmt.Errorf("could not create invocation result request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if errorType != "" {
+			req.Header.Set("Lambda-Runtime-Function-Error-Type", errorType)
+		}
+		rsp, err = client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("could not post invocation result: %w", err)
+		}
+		// the result of an invocation which has already timed out is rejected, which the runtime continues after
+		_, _ = io.Copy(io.Discard, rsp.Body)
+		rsp.Body.Close()
+	}
+}
+
+// invoke invokes the handler with an invocation from the Runtime API, returning the response, or an ErrorResponse
+// and its type
+func invoke(ctx context.Context, h lambda.Handler, header http.Header, payload []byte) (res []byte, errorType string) {
+	deadlineMs, _ := strconv.ParseInt(header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64)
+	ctx, cancel := context.WithDeadline(ctx, time.UnixMilli(deadlineMs))
+	defer cancel()
+	lc := &lambdacontext.LambdaContext{
+		AwsRequestID:       header.Get("Lambda-Runtime-Aws-Request-Id"),
+		InvokedFunctionArn: header.Get("Lambda-Runtime-Invoked-Function-Arn"),
+	}
+	if clientContext := header.Get("Lambda-Runtime-Client-Context"); clientContext != "" {
+		_ = json.Unmarshal([]byte(clientContext), &lc.ClientContext)
+	}
+	if identity := header.Get("Lambda-Runtime-Cognito-Identity"); identity != "" {
+		_ = json.Unmarshal([]byte(identity), &lc.Identity)
+	}
+	ctx = lambdacontext.NewContext(ctx, lc)
+	ctx = context.WithValue(ctx, "x-amzn-trace-id", header.Get("Lambda-Runtime-Trace-Id"))
+
+	defer func() {
+		if r := recover(); r != nil {
+			res, _ = json.Marshal(ErrorResponse{ErrorMessage: fmt.Sprint(r), ErrorType: typeName(r), StackTrace: panicStack()})
+			errorType = "Unhandled"
+		}
+	}()
+	res, err := h.Invoke(ctx, payload)
+	if err != nil {
+		res, _ = json.Marshal(ErrorResponse{ErrorMessage: err.Error(), ErrorType: typeName(err)})
+		return res, "Unhandled"
+	}
+	return res, ""
+}
+
+// typeName returns the name of the type of the value, as Lambda reports the type of errors
+func typeName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// panicStack returns the stack of the panic being recovered, without the frames of the runtime
+func panicStack() []StackFrame {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs) // skip runtime.Callers, panicStack and the deferred function
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []StackFrame
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append(stack, StackFrame{Path: frame.File, Line: frame.Line, Label: frame.Function})
+		}
+		if !more {
+			return stack
+		}
+	}
+}
@@ -0,0 +1,381 @@
+// Package local emulates the Lambda service on localhost, so functions can be invoked with the AWS CLI, SDKs or
+// other tooling without deploying them. A Server serves the Invoke API, which clients call to invoke a function, and
+// the Runtime API, which the runtime of the function polls for invocations. ListenAndServe runs both with a Vesper
+// function as the runtime:
+//
+//	v := vesper.New(MyHandler, vesper.CorrelationIDMiddleware())
+//	log.Fatal(local.ListenAndServe("localhost:9001", v, local.WithTimeout(10*time.Second)))
+//
+// and the function can then be invoked with:
+//
+//	aws lambda invoke --endpoint-url http://localhost:9001 --function-name users --payload '{}' out.json
+package local
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+const (
+	// DefaultTimeout is the timeout of invocations unless set with WithTimeout, which is the Lambda default
+	DefaultTimeout = 3 * time.Second
+	// DefaultRegion is the region of function ARNs unless set with WithRegion
+	DefaultRegion = "us-east-1"
+	// DefaultAccountID is the account ID of function ARNs unless set with WithAccountID
+	DefaultAccountID = "123456789012"
+	// MaxResponseSize is the largest response a synchronous invocation can return, as in Lambda
+	MaxResponseSize = 6 * 1024 * 1024
+	// MaxQueuedEvents is the number of Event invocations queued for the runtime, beyond which they are throttled
+	MaxQueuedEvents = 100
+
+	invokePrefix  = "/2015-03-31/functions/"
+	runtimePrefix = "/2018-06-01/runtime/invocation/"
+)
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithTimeout sets the timeout of invocations, after which the invocation fails as timed out
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.timeout = timeout
+	}
+}
+
+// WithRegion sets the region of the ARNs functions are invoked with
+func WithRegion(region string) Option {
+	return func(s *Server) {
+		s.region = region
+	}
+}
+
+// WithAccountID sets the account ID of the ARNs functions are invoked with
+func WithAccountID(accountID string) Option {
+	return func(s *Server) {
+		s.accountID = accountID
+	}
+}
+
+// ErrorResponse is the body of the response of a failed invocation, as returned by Lambda
+type ErrorResponse struct {
+	ErrorMessage string       `json:"errorMessage"`
+	ErrorType    string       `json:"errorType,omitempty"`
+	StackTrace   []StackFrame `json:"stackTrace,omitempty"`
+}
+
+// StackFrame is a frame of the stack trace of a panic in the error response
+type StackFrame struct {
+	Path  string `json:"path"`
+	Line  int    `json:"line"`
+	Label string `json:"label"`
+}
+
+// Server is an http.Handler serving the Lambda Invoke API and Runtime API. Each invocation made with the Invoke API
+// is queued until a runtime takes it from the Runtime API, and a synchronous invocation responds with the response or
+// error the runtime posts for it, or a timeout error if it is not posted before the deadline. Event invocations are
+// accepted once queued, and throttled when MaxQueuedEvents are already waiting for a runtime. Every invocation taken
+// by a runtime, including Event invocations, fails once its deadline passes without a response being posted.
+// Invocations are given to one runtime at a time, and any function name can be invoked.
+type Server struct {
+	timeout   time.Duration
+	region    string
+	accountID string
+
+	pending  chan *invocation
+	events   chan *invocation
+	mu       sync.Mutex
+	inflight map[string]*invocation
+}
+
+type invocation struct {
+	requestID     string
+	functionARN   string
+	payload       []byte
+	traceID       string
+	clientContext string
+	deadline      time.Time
+	timer         *time.Timer
+	done          chan result
+}
+
+type result struct {
+	payload   []byte
+	errorType string
+}
+
+// NewServer creates a Server
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		timeout:   DefaultTimeout,
+		region:    DefaultRegion,
+		accountID: DefaultAccountID,
+		pending:   make(chan *invocation),
+		events:    make(chan *invocation, MaxQueuedEvents),
+		inflight:  map[string]*invocation{},
+	}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// ListenAndServe serves the Invoke API and Runtime API on the address, and runs the handler as the runtime of the
+// function in this process. It returns when the server fails, like http.ListenAndServe.
+func ListenAndServe(addr string, h lambda.Handler, options ...Option) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", addr, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtimeErr := make(chan error, 1)
+	go func() {
+		runtimeErr <- Run(ctx, l.Addr().String(), h)
+	}()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- http.Serve(l, NewServer(options...))
+	}()
+	select {
+	case err := <-serveErr:
+		return err
+	case err := <-runtimeErr:
+		l.Close()
+		return err
+	}
+}
+
+// ServeHTTP serves the Invoke API and Runtime API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, invokePrefix) && strings.HasSuffix(r.URL.Path, "/invocations"):
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, invokePrefix), "/invocations")
+		if name == "" || strings.Contains(name, "/") {
+			writeAPIError(w, http.StatusNotFound, "ResourceNotFoundException", "function not found")
+			return
+		}
+		s.invoke(w, r, name)
+	case r.URL.Path == runtimePrefix+"next":
+		s.next(w, r)
+	case strings.HasPrefix(r.URL.Path, runtimePrefix) && strings.HasSuffix(r.URL.Path, "/response"):
+		s.respond(w, r, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, runtimePrefix), "/response"), "")
+	case strings.HasPrefix(r.URL.Path, runtimePrefix) && strings.HasSuffix(r.URL.Path, "/error"):
+		s.respond(w, r, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, runtimePrefix), "/error"), "Unhandled")
+	default:
+		writeAPIError(w, http.StatusNotFound, "ResourceNotFoundException", fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// invoke serves the Invoke API, POST /2015-03-31/functions/{name}/invocations
+func (s *Server) invoke(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "InvalidRequestContentException", "invocations must be made with POST")
+		return
+	}
+	invocationType := r.Header.Get("X-Amz-Invocation-Type")
+	if invocationType != "" && invocationType != "RequestResponse" && invocationType != "Event" && invocationType != "DryRun" {
+		writeAPIError(w, http.StatusBadRequest, "InvalidParameterValueException", fmt.Sprintf("unsupported invocation type %q", invocationType))
+		return
+	}
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "InvalidRequestContentException", fmt.Sprintf("could not read payload: %s", err))
+		return
+	}
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	if !json.Valid(payload) {
+		writeAPIError(w, http.StatusBadRequest, "InvalidRequestContentException", "could not parse request body into json")
+		return
+	}
+	var clientContext string
+	if header := r.Header.Get("X-Amz-Client-Context"); header != "" {
+		decoded, err := base64.StdEncoding.DecodeString(header)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "InvalidRequestContentException", "client context must be base64 encoded")
+			return
+		}
+		clientContext = string(decoded)
+	}
+
+	inv := &invocation{
+		requestID:     newRequestID(),
+		functionARN:   s.functionARN(name),
+		payload:       payload,
+		traceID:       newTraceID(),
+		clientContext: clientContext,
+		done:          make(chan result, 1),
+	}
+	w.Header().Set("X-Amzn-Requestid", inv.requestID)
+
+	switch invocationType {
+	case "DryRun":
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case "Event":
+		select {
+		case s.events <- inv:
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			writeAPIError(w, http.StatusTooManyRequests, "TooManyRequestsException", fmt.Sprintf("more than %d Event invocations are queued", MaxQueuedEvents))
+		}
+		return
+	}
+
+	select {
+	case s.pending <- inv:
+	case <-r.Context().Done():
+		return
+	}
+
+	var res result
+	select {
+	case res = <-inv.done:
+	case <-r.Context().Done():
+		return
+	}
+	if res.errorType == "" && len(res.payload) > MaxResponseSize {
+		res.errorType = "Unhandled"
+		res.payload, _ = json.Marshal(ErrorResponse{
+			ErrorMessage: fmt.Sprintf("Response payload size (%d bytes) exceeded maximum allowed payload size (%d bytes).", len(res.payload), MaxResponseSize),
+			ErrorType:    "Function.ResponseSizeTooLarge",
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amz-Executed-Version", "$LATEST")
+	if res.errorType != "" {
+		w.Header().Set("X-Amz-Function-Error", res.errorType)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(res.payload)
+}
+
+// next serves GET /2018-06-01/runtime/invocation/next, which waits for the next invocation
+func (s *Server) next(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeRuntimeError(w, http.StatusMethodNotAllowed, "InvalidRequest", "the next invocation must be requested with GET")
+		return
+	}
+	var inv *invocation
+	select {
+	case inv = <-s.pending:
+	case inv = <-s.events:
+	case <-r.Context().Done():
+		return
+	}
+
+	inv.deadline = time.Now().Add(s.timeout)
+	s.mu.Lock()
+	s.inflight[inv.requestID] = inv
+	inv.timer = time.AfterFunc(s.timeout, func() {
+		s.timedOut(inv)
+	})
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Lambda-Runtime-Aws-Request-Id", inv.requestID)
+	w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(inv.deadline.UnixMilli(), 10))
+	w.Header().Set("Lambda-Runtime-Invoked-Function-Arn", inv.functionARN)
+	w.Header().Set("Lambda-Runtime-Trace-Id", inv.traceID)
+	if inv.clientContext != "" {
+		w.Header().Set("Lambda-Runtime-Client-Context", inv.clientContext)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(inv.payload)
+}
+
+// respond serves POST /2018-06-01/runtime/invocation/{id}/response and /error, which complete an invocation
+func (s *Server) respond(w http.ResponseWriter, r *http.Request, requestID, errorType string) {
+	if r.Method != http.MethodPost {
+		writeRuntimeError(w, http.StatusMethodNotAllowed, "InvalidRequest", "invocation results must be posted")
+		return
+	}
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeRuntimeError(w, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("could not read body: %s", err))
+		return
+	}
+	inv := s.remove(requestID)
+	if inv == nil {
+		writeRuntimeError(w, http.StatusBadRequest, "InvalidRequestID", fmt.Sprintf("invocation %s is not in progress", requestID))
+		return
+	}
+	inv.timer.Stop()
+	inv.done <- result{payload: payload, errorType: errorType}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte(`{"status":"OK"}`))
+}
+
+// timedOut fails an invocation which is still in progress at its deadline with a timeout error, so it is no longer
+// in progress whether or not a client is waiting for its result
+func (s *Server) timedOut(inv *invocation) {
+	if s.remove(inv.requestID) == nil {
+		return // the runtime responded in time
+	}
+	payload, _ := json.Marshal(ErrorResponse{
+		ErrorMessage: fmt.Sprintf("RequestId: %s Error: Task timed out after %.2f seconds", inv.requestID, s.timeout.Seconds()),
+		ErrorType:    "Sandbox.Timedout",
+	})
+	inv.done <- result{payload: payload, errorType: "Unhandled"}
+}
+
+// remove removes the invocation from those in progress, returning nil if it is not in progress
+func (s *Server) remove(requestID string) *invocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv := s.inflight[requestID]
+	delete(s.inflight, requestID)
+	return inv
+}
+
+// functionARN returns the ARN a function is invoked with, which can be named by its name or ARN
+func (s *Server) functionARN(name string) string {
+	if strings.HasPrefix(name, "arn:") {
+		return name
+	}
+	return fmt.Sprintf("arn:aws:lambda:%s:%s:function:%s", s.region, s.accountID, name)
+}
+
+// writeAPIError writes an error response of the Invoke API
+func writeAPIError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amzn-Errortype", errorType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"Type": "User", "message": message})
+}
+
+// writeRuntimeError writes an error response of the Runtime API
+func writeRuntimeError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{ErrorMessage: message, ErrorType: errorType})
+}
+
+// newRequestID generates a random request ID in the format used by Lambda
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// newTraceID generates an X-Ray trace header for an invocation which is not sampled
+func newTraceID() string {
+	var b [20]byte
+	_, _ = rand.Read(b[:])
+	return fmt.Sprintf("Root=1-%08x-%x;Parent=%x;Sampled=0", time.Now().Unix(), b[:12], b[12:])
+}
@@ -0,0 +1,249 @@
+package local
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/mefellows/vesper"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func startServer(t *testing.T, v *vesper.Vesper, options ...Option) string {
+	ts := httptest.NewServer(NewServer(options...))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, strings.TrimPrefix(ts.URL, "http://"), v)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.Equal(t, context.Canceled, <-done)
+		ts.Close()
+	})
+	return ts.URL
+}
+
+func invokeFunction(t *testing.T, url, function, payload string, header http.Header) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodPost, url+"/2015-03-31/functions/"+function+"/invocations", strings.NewReader(payload))
+	assert.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer rsp.Body.Close()
+	body, err := io.ReadAll(rsp.Body)
+	assert.NoError(t, err)
+	return rsp, string(body)
+}
+
+func TestServer(t *testing.T) {
+	h := func(ctx context.Context, u user) (string, error) {
+		switch u.Name {
+		case "fail":
+			return "", errors.New("failed")
+		case "panic":
+			panic("boom")
+		case "slow":
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			return "", ctx.Err()
+		case "context":
+			lc, _ := lambdacontext.FromContext(ctx)
+			deadline, _ := ctx.Deadline()
+			b, _ := json.Marshal(map[string]interface{}{
+				"requestId":   lc.AwsRequestID,
+				"functionArn": lc.InvokedFunctionArn,
+				"app":         lc.ClientContext.Client.AppTitle,
+				"trace":       ctx.Value("x-amzn-trace-id"),
+				"remaining":   time.Until(deadline).Milliseconds(),
+			})
+			return string(b), nil
+		}
+		return "hello " + u.Name, nil
+	}
+	url := startServer(t, vesper.New(h), WithTimeout(200*time.Millisecond), WithRegion("eu-west-1"))
+
+	t.Run("response", func(t *testing.T) {
+		rsp, body := invokeFunction(t, url, "users", `{"name": "bob"}`, nil)
+		assert.Equal(t, http.StatusOK, rsp.StatusCode)
+		assert.Equal(t, `"hello bob"`, body)
+		assert.Empty(t, rsp.Header.Get("X-Amz-Function-Error"))
+		assert.NotEmpty(t, rsp.Header.Get("X-Amzn-Requestid"))
+	})
+
+	t.Run("lambda context", func(t *testing.T) {
+		clientContext := base64.StdEncoding.EncodeToString([]byte(`{"client": {"app_title": "app"}}`))
+		rsp, body := invokeFunction(t, url, "users", `{"name": "context"}`, http.Header{"X-Amz-Client-Context": {clientContext}})
+		var s string
+		assert.NoError(t, json.Unmarshal([]byte(body), &s))
+		var lc map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(s), &lc))
+		assert.Equal(t, rsp.Header.Get("X-Amzn-Requestid"), lc["requestId"])
+		assert.Equal(t, "arn:aws:lambda:eu-west-1:123456789012:function:users", lc["functionArn"])
+		assert.Equal(t, "app", lc["app"])
+		assert.Regexp(t, `^Root=1-[0-9a-f]{8}-[0-9a-f]{24};Parent=[0-9a-f]{16};Sampled=0$`, lc["trace"])
+		assert.InDelta(t, 200, lc["remaining"], 50)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		rsp, body := invokeFunction(t, url, "users", `{"name": "fail"}`, nil)
+		assert.Equal(t, http.StatusOK, rsp.StatusCode)
+		assert.Equal(t, "Unhandled", rsp.Header.Get("X-Amz-Function-Error"))
+		assert.JSONEq(t, `{"errorMessage": "failed", "errorType": "errorString"}`, body)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		rsp, body := invokeFunction(t, url, "users", `{"name": "panic"}`, nil)
+		assert.Equal(t, "Unhandled", rsp.Header.Get("X-Amz-Function-Error"))
+		var errRsp ErrorResponse
+		assert.NoError(t, json.Unmarshal([]byte(body), &errRsp))
+		assert.Equal(t, "boom", errRsp.ErrorMessage)
+		assert.Equal(t, "string", errRsp.ErrorType)
+		assert.NotEmpty(t, errRsp.StackTrace)
+		assert.Contains(t, errRsp.StackTrace[0].Label, "TestServer")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		rsp, body := invokeFunction(t, url, "users", `{"name": "slow"}`, nil)
+		assert.Equal(t, "Unhandled", rsp.Header.Get("X-Amz-Function-Error"))
+		assert.JSONEq(t, `{"errorMessage": "RequestId: `+rsp.Header.Get("X-Amzn-Requestid")+` Error: Task timed out after 0.20 seconds", "errorType": "Sandbox.Timedout"}`, body)
+
+		// the late response of the timed out invocation is rejected, and the runtime continues with the next
+		rsp, body = invokeFunction(t, url, "users", `{"name": "bob"}`, nil)
+		assert.Equal(t, `"hello bob"`, body)
+	})
+
+	t.Run("invocation types", func(t *testing.T) {
+		rsp, _ := invokeFunction(t, url, "users", `{"name": "bob"}`, http.Header{"X-Amz-Invocation-Type": {"DryRun"}})
+		assert.Equal(t, http.StatusNoContent, rsp.StatusCode)
+		rsp, _ = invokeFunction(t, url, "users", `{"name": "bob"}`, http.Header{"X-Amz-Invocation-Type": {"Event"}})
+		assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+		rsp, body := invokeFunction(t, url, "users", `{"name": "bob"}`, http.Header{"X-Amz-Invocation-Type": {"Later"}})
+		assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+		assert.Equal(t, "InvalidParameterValueException", rsp.Header.Get("X-Amzn-Errortype"))
+		assert.Contains(t, body, `unsupported invocation type \"Later\"`)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		rsp, _ := invokeFunction(t, url, "users", `{`, nil)
+		assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+		assert.Equal(t, "InvalidRequestContentException", rsp.Header.Get("X-Amzn-Errortype"))
+
+		rsp, err := http.Get(url + "/2015-03-31/functions/users/invocations")
+		assert.NoError(t, err)
+		rsp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, rsp.StatusCode)
+
+		rsp, err = http.Post(url+"/2018-06-01/runtime/invocation/unknown/response", "application/json", strings.NewReader(`{}`))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+		assert.JSONEq(t, `{"errorMessage": "invocation unknown is not in progress", "errorType": "InvalidRequestID"}`, string(body))
+	})
+}
+
+func TestServerRuntimeAPI(t *testing.T) {
+	ts := httptest.NewServer(NewServer(WithTimeout(time.Second)))
+	defer ts.Close()
+
+	type invocation struct {
+		rsp  *http.Response
+		body string
+	}
+	invoked := make(chan invocation)
+	go func() {
+		rsp, body := invokeFunction(t, ts.URL, "arn:aws:lambda:eu-west-1:210987654321:function:orders", `{"id": 1}`, nil)
+		invoked <- invocation{rsp, body}
+	}()
+
+	rsp, err := http.Get(ts.URL + "/2018-06-01/runtime/invocation/next")
+	assert.NoError(t, err)
+	payload, _ := io.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	assert.JSONEq(t, `{"id": 1}`, string(payload))
+	assert.Equal(t, "arn:aws:lambda:eu-west-1:210987654321:function:orders", rsp.Header.Get("Lambda-Runtime-Invoked-Function-Arn"))
+	deadline, err := strconv.ParseInt(rsp.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Second), time.UnixMilli(deadline), 100*time.Millisecond)
+
+	requestID := rsp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+	rsp, err = http.Post(ts.URL+"/2018-06-01/runtime/invocation/"+requestID+"/error", "application/json",
+		strings.NewReader(`{"errorMessage": "invalid order", "errorType": "Runtime.Unknown"}`))
+	assert.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+
+	result := <-invoked
+	assert.Equal(t, requestID, result.rsp.Header.Get("X-Amzn-Requestid"))
+	assert.Equal(t, "Unhandled", result.rsp.Header.Get("X-Amz-Function-Error"))
+	assert.JSONEq(t, `{"errorMessage": "invalid order", "errorType": "Runtime.Unknown"}`, result.body)
+}
+
+func TestServerEventQueue(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+
+	// Event invocations are queued without a runtime polling, until the queue is full
+	for i := 0; i < MaxQueuedEvents; i++ {
+		rsp, _ := invokeFunction(t, ts.URL, "users", `{"id": `+strconv.Itoa(i)+`}`, http.Header{"X-Amz-Invocation-Type": {"Event"}})
+		assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+	}
+	rsp, body := invokeFunction(t, ts.URL, "users", `{"id": 100}`, http.Header{"X-Amz-Invocation-Type": {"Event"}})
+	assert.Equal(t, http.StatusTooManyRequests, rsp.StatusCode)
+	assert.Equal(t, "TooManyRequestsException", rsp.Header.Get("X-Amzn-Errortype"))
+	assert.Contains(t, body, "more than 100 Event invocations are queued")
+
+	rsp, err := http.Get(ts.URL + "/2018-06-01/runtime/invocation/next")
+	assert.NoError(t, err)
+	payload, _ := io.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	assert.JSONEq(t, `{"id": 0}`, string(payload))
+
+	rsp, err = http.Post(ts.URL+"/2018-06-01/runtime/invocation/"+rsp.Header.Get("Lambda-Runtime-Aws-Request-Id")+"/response", "application/json", strings.NewReader(`null`))
+	assert.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+
+	rsp, _ = invokeFunction(t, ts.URL, "users", `{"id": 100}`, http.Header{"X-Amz-Invocation-Type": {"Event"}})
+	assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+}
+
+func TestServerEventTimeout(t *testing.T) {
+	s := NewServer(WithTimeout(100 * time.Millisecond))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	rsp, _ := invokeFunction(t, ts.URL, "users", `{}`, http.Header{"X-Amz-Invocation-Type": {"Event"}})
+	assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+	rsp, err := http.Get(ts.URL + "/2018-06-01/runtime/invocation/next")
+	assert.NoError(t, err)
+	rsp.Body.Close()
+	requestID := rsp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+
+	// the runtime does not respond before the deadline, so the invocation is no longer in progress
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.inflight) == 0
+	}, time.Second, 10*time.Millisecond)
+	rsp, err = http.Post(ts.URL+"/2018-06-01/runtime/invocation/"+requestID+"/response", "application/json", strings.NewReader(`null`))
+	assert.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+}
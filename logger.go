@@ -1,15 +1,159 @@
 package vesper
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// StructuredLogger is a leveled logger which attaches key/value pairs to each message, e.g.
+//
+//	logger.Info("user created", "userID", u.ID)
+//
+// Every invocation is given a logger with the AWS request ID, function name and version and cold start flag attached,
+// which handlers and middleware retrieve with LoggerFromContext.
+type StructuredLogger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+	// With returns a logger which attaches the key/value pairs to every message
+	With(keyvals ...interface{}) StructuredLogger
+}
+
+// NewSlogLogger adapts a log/slog Logger into a StructuredLogger
+func NewSlogLogger(l *slog.Logger) StructuredLogger {
+	return slogLogger{l: l}
+}
+
+// NewJSONLogger creates a StructuredLogger which writes messages of at least the given level to w as JSON lines,
+// which CloudWatch Logs Insights discovers the fields of automatically.
+func NewJSONLogger(w io.Writer, level slog.Leveler) StructuredLogger {
+	return NewSlogLogger(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})))
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debug(msg string, keyvals ...interface{}) { s.l.Debug(msg, keyvals...) }
+
+func (s slogLogger) Info(msg string, keyvals ...interface{}) { s.l.Info(msg, keyvals...) }
+
+func (s slogLogger) Warn(msg string, keyvals ...interface{}) { s.l.Warn(msg, keyvals...) }
+
+func (s slogLogger) Error(msg string, keyvals ...interface{}) { s.l.Error(msg, keyvals...) }
+
+func (s slogLogger) With(keyvals ...interface{}) StructuredLogger {
+	return slogLogger{l: s.l.With(keyvals...)}
+}
+
+type noOpLogger struct{}
+
+func (noOpLogger) Debug(string, ...interface{}) {}
+
+func (noOpLogger) Info(string, ...interface{}) {}
+
+func (noOpLogger) Warn(string, ...interface{}) {}
+
+func (noOpLogger) Error(string, ...interface{}) {}
+
+func (n noOpLogger) With(...interface{}) StructuredLogger { return n }
+
 type logPrinter interface {
 	Print(v ...interface{})
 	Printf(format string, v ...interface{})
 	Println(v ...interface{})
 }
 
-type noOpPrinter struct{}
+// printerLogger adapts a logPrinter, such as a *log.Logger, into a StructuredLogger which writes
+// messages as text, e.g. "INFO user created userID=1"
+type printerLogger struct {
+	p       logPrinter
+	keyvals []interface{}
+}
+
+func (p printerLogger) Debug(msg string, keyvals ...interface{}) { p.print("DEBUG", msg, keyvals) }
+
+func (p printerLogger) Info(msg string, keyvals ...interface{}) { p.print("INFO", msg, keyvals) }
 
-func (n noOpPrinter) Print(...interface{}) {}
+func (p printerLogger) Warn(msg string, keyvals ...interface{}) { p.print("WARN", msg, keyvals) }
+
+func (p printerLogger) Error(msg string, keyvals ...interface{}) { p.print("ERROR", msg, keyvals) }
+
+func (p printerLogger) With(keyvals ...interface{}) StructuredLogger {
+	return printerLogger{p: p.p, keyvals: append(append([]interface{}{}, p.keyvals...), keyvals...)}
+}
+
+func (p printerLogger) print(level string, msg string, keyvals []interface{}) {
+	var sb strings.Builder
+	sb.WriteString(level)
+	sb.WriteString(" ")
+	sb.WriteString(msg)
+	all := append(append([]interface{}{}, p.keyvals...), keyvals...)
+	for i := 0; i < len(all); i += 2 {
+		if i+1 < len(all) {
+			fmt.Fprintf(&sb, " %v=%v", all[i], all[i+1])
+		} else {
+			fmt.Fprintf(&sb, " %v", all[i])
+		}
+	}
+	p.p.Println(sb.String())
+}
 
-func (n noOpPrinter) Printf(string, ...interface{}) {}
+var (
+	defaultLoggerMu sync.RWMutex
+	defaultLogger   StructuredLogger = noOpLogger{}
+)
 
-func (n noOpPrinter) Println(...interface{}) {}
+// Logger sets the log to use for Vesper instances created afterwards, unless they are given a logger with UseLogger
+// or WithLogger. Messages are printed as text with their level and key/value pairs.
+//
+// Deprecated: use UseLogger to give each Vesper instance a StructuredLogger.
+func Logger(l logPrinter) {
+	defaultLoggerMu.Lock()
+	defer defaultLoggerMu.Unlock()
+	if l == nil {
+		defaultLogger = noOpLogger{}
+		return
+	}
+	defaultLogger = printerLogger{p: l}
+}
+
+func getDefaultLogger() StructuredLogger {
+	defaultLoggerMu.RLock()
+	defer defaultLoggerMu.RUnlock()
+	return defaultLogger
+}
+
+// LoggerFromContext retrieves the logger of the current invocation from a context.
+// Unlike the other context accessors it always returns a usable logger, which discards messages if
+// the context is not from an invocation.
+func LoggerFromContext(ctx context.Context) StructuredLogger {
+	if l, ok := ctx.Value(ctxKeyLogger).(StructuredLogger); ok {
+		return l
+	}
+	return noOpLogger{}
+}
+
+// middlewareLogger retrieves the logger of the current invocation with the name of a middleware attached
+func middlewareLogger(ctx context.Context, name string) StructuredLogger {
+	return LoggerFromContext(ctx).With("middleware", name)
+}
+
+// invocationLogger attaches the fields of an invocation to the logger
+func invocationLogger(ctx context.Context, l StructuredLogger, coldStart bool) StructuredLogger {
+	keyvals := []interface{}{"coldStart", coldStart}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		keyvals = append(keyvals, "awsRequestId", lc.AwsRequestID)
+	}
+	if lambdacontext.FunctionName != "" {
+		keyvals = append(keyvals, "functionName", lambdacontext.FunctionName, "functionVersion", lambdacontext.FunctionVersion)
+	}
+	return l.With(keyvals...)
+}
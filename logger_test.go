@@ -0,0 +1,68 @@
+package vesper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := func(ctx context.Context, in string) (string, error) {
+		LoggerFromContext(ctx).Info("handling", "in", in)
+		return in, nil
+	}
+	v := New(h).UseLogger(NewJSONLogger(&buf, slog.LevelInfo))
+	handler := v.buildHandler()
+
+	for _, id := range []string{"req-1", "req-2"} {
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: id})
+		_, err := handler.Invoke(ctx, []byte(`"hello"`))
+		assert.NoError(t, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	for i, expected := range []map[string]interface{}{
+		{"level": "INFO", "msg": "handling", "in": "hello", "coldStart": true, "awsRequestId": "req-1"},
+		{"level": "INFO", "msg": "handling", "in": "hello", "coldStart": false, "awsRequestId": "req-2"},
+	} {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[i]), &entry))
+		delete(entry, "time")
+		assert.Equal(t, expected, entry)
+	}
+}
+
+func TestMiddlewareLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := func(ctx context.Context) (string, error) {
+		return "ok", nil
+	}
+	v := New(h, WarmupMiddleware).UseLogger(NewJSONLogger(&buf, slog.LevelInfo))
+	_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"Event": {"source": "serverless-plugin-warmup"}}`))
+	assert.NoError(t, err)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "warmup event detected, exiting", entry["msg"])
+	assert.Equal(t, "WarmupMiddleware", entry["middleware"])
+}
+
+func TestLoggerFromContextWithoutInvocation(t *testing.T) {
+	assert.Equal(t, noOpLogger{}, LoggerFromContext(context.Background()))
+}
+
+func TestPrinterLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := printerLogger{p: log.New(&buf, "", 0)}
+	l.With("coldStart", true).Warn("retrying", "attempt", 2, "dangling")
+	assert.Equal(t, "WARN retrying coldStart=true attempt=2 dangling\n", buf.String())
+}
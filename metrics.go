@@ -0,0 +1,88 @@
+package vesper
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/mefellows/vesper/metrics"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MetricsMiddleware is a middleware which records metrics for each invocation, and emits them with the emitter in
+// the CloudWatch Embedded Metric Format before the invocation returns. Handlers and middleware can record their own
+// metrics with metrics.FromContext. Every invocation records:
+//
+//   - Invocations: 1
+//   - Duration: the time taken by the rest of the chain, in milliseconds
+//   - ColdStart: 1 for the first invocation of the execution environment, otherwise 0
+//   - Errors: 1 if an error was returned or a panic occurred, otherwise 0
+//
+// Metrics have a FunctionName dimension when running in Lambda, and the request ID, and trace ID if the invocation
+// is traced, are included as properties so the log entry can be found from them.
+// It is added as the outermost middleware by the WithMetrics option, which also records latency metrics for each
+// middleware.
+func MetricsMiddleware(emitter *metrics.Emitter) func(LambdaFunc) LambdaFunc {
+	return NamedMiddleware("MetricsMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (res interface{}, err error) {
+			m := metrics.New()
+			if lambdacontext.FunctionName != "" {
+				m.SetDimension("FunctionName", lambdacontext.FunctionName)
+			}
+			if lc, ok := lambdacontext.FromContext(ctx); ok {
+				m.SetProperty("requestId", lc.AwsRequestID)
+			}
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				m.SetProperty("traceId", sc.TraceID().String())
+			}
+			coldStart, _ := ctx.Value(ctxKeyColdStart).(bool)
+			ctx = metrics.NewContext(ctx, m)
+
+			start := time.Now()
+			panicked := true
+			defer func() {
+				m.Put("Invocations", 1, metrics.UnitCount)
+				m.Put("Duration", milliseconds(time.Since(start)), metrics.UnitMilliseconds)
+				m.Put("ColdStart", boolMetric(coldStart), metrics.UnitCount)
+				m.Put("Errors", boolMetric(err != nil || panicked), metrics.UnitCount)
+				if emitErr := emitter.Emit(m); emitErr != nil {
+					middlewareLogger(ctx, "MetricsMiddleware").Warn("could not emit metrics", "error", emitErr)
+				}
+			}()
+			res, err = next(ctx, in)
+			panicked = false
+			return res, err
+		}
+	})
+}
+
+// measureLambdaFunc records the latency of f as a metric of the invocation. When f is a middleware, the latency
+// excludes the rest of the chain, and the metric has a Middleware dimension.
+func measureLambdaFunc(metric string, f LambdaFunc) LambdaFunc {
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		m := metrics.FromContext(ctx)
+		if m == nil {
+			return f(ctx, in) // continue as there are no invocation metrics to add to
+		}
+		start := time.Now()
+		defer func() {
+			if im := instrumentedMiddlewareFromContext(ctx); im != nil {
+				m.PutWithDimensions(metric, milliseconds(im.Exclusive()), metrics.UnitMilliseconds, map[string]string{"Middleware": im.Name()})
+				return
+			}
+			m.Put(metric, milliseconds(time.Since(start)), metrics.UnitMilliseconds)
+		}()
+		return f(ctx, in)
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
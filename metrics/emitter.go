@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// CloudWatch limits of a single EMF log entry
+const (
+	maxMetricsPerEntry = 100
+	maxValuesPerMetric = 100
+)
+
+// Emitter writes metrics as CloudWatch Embedded Metric Format log entries
+type Emitter struct {
+	namespace string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewEmitter creates an Emitter which writes metrics into the given CloudWatch namespace.
+// If w is nil metrics are written to stdout, which the Lambda runtime sends to CloudWatch Logs.
+func NewEmitter(namespace string, w io.Writer) *Emitter {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &Emitter{namespace: namespace, w: w}
+}
+
+// Emit writes the metrics as one line of JSON for each set of dimensions, splitting them into several lines
+// where they exceed the limits of a single log entry. Nothing is written if no metrics were recorded.
+func (e *Emitter) Emit(m *Metrics) error {
+	if m == nil {
+		return nil
+	}
+	entries := e.entries(m, time.Now())
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("could not marshal metrics: %w", err)
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("could not write metrics: %w", err)
+	}
+	return nil
+}
+
+type metricDefinition struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit"`
+}
+
+type metricDirective struct {
+	Namespace  string             `json:"Namespace"`
+	Dimensions [][]string         `json:"Dimensions"`
+	Metrics    []metricDefinition `json:"Metrics"`
+}
+
+type metadata struct {
+	Timestamp         int64             `json:"Timestamp"`
+	CloudWatchMetrics []metricDirective `json:"CloudWatchMetrics"`
+}
+
+// entries converts the metrics into EMF log entries
+func (e *Emitter) entries(m *Metrics, now time.Time) []map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// group the series by their full set of dimensions, keeping the order they were first recorded in
+	type group struct {
+		dimensions map[string]string
+		series     []*series
+	}
+	var groups []*group
+	byKey := map[string]*group{}
+	for _, s := range m.series {
+		dimensions := make(map[string]string, len(m.dimensions)+len(s.dimensions))
+		for k, v := range m.dimensions {
+			dimensions[k] = v
+		}
+		for k, v := range s.dimensions {
+			dimensions[k] = v
+		}
+		key := dimensionKey(dimensions)
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{dimensions: dimensions}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.series = append(g.series, s)
+	}
+
+	var entries []map[string]interface{}
+	for _, g := range groups {
+		written := make([]int, len(g.series))
+		for {
+			entry := map[string]interface{}{}
+			for k, v := range m.properties {
+				entry[k] = v
+			}
+			for k, v := range g.dimensions {
+				entry[k] = v
+			}
+			var definitions []metricDefinition
+			for i, s := range g.series {
+				if written[i] == len(s.values) || len(definitions) == maxMetricsPerEntry {
+					continue
+				}
+				end := written[i] + maxValuesPerMetric
+				if end > len(s.values) {
+					end = len(s.values)
+				}
+				values := s.values[written[i]:end]
+				written[i] = end
+				if len(values) == 1 {
+					entry[s.name] = values[0]
+				} else {
+					entry[s.name] = append([]float64(nil), values...)
+				}
+				definitions = append(definitions, metricDefinition{Name: s.name, Unit: s.unit})
+			}
+			if len(definitions) == 0 {
+				break
+			}
+			entry["_aws"] = metadata{
+				Timestamp: now.UnixMilli(),
+				CloudWatchMetrics: []metricDirective{{
+					Namespace:  e.namespace,
+					Dimensions: [][]string{sortedKeys(g.dimensions)},
+					Metrics:    definitions,
+				}},
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
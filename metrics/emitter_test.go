@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmitter(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter("Vesper", &buf)
+	assert.NoError(t, e.Emit(New()))
+	assert.NoError(t, e.Emit(nil))
+	assert.Empty(t, buf.String())
+
+	m := New()
+	m.SetDimension("FunctionName", "orders")
+	m.SetProperty("requestId", "request-1")
+	m.Put("Orders", 1, UnitCount)
+	m.Put("Orders", 2, UnitCount)
+	m.Put("Duration", 12.5, UnitMilliseconds)
+	m.PutWithDimensions("Latency", 10, UnitMilliseconds, map[string]string{"Dependency": "db"})
+	assert.NoError(t, e.Emit(m))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	var first map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	timestamp := first["_aws"].(map[string]interface{})["Timestamp"].(float64)
+	assert.InDelta(t, time.Now().UnixMilli(), timestamp, 60000)
+	assert.JSONEq(t, fmt.Sprintf(`{
+		"_aws": {
+			"Timestamp": %.0f,
+			"CloudWatchMetrics": [{
+				"Namespace": "Vesper",
+				"Dimensions": [["FunctionName"]],
+				"Metrics": [{"Name": "Orders", "Unit": "Count"}, {"Name": "Duration", "Unit": "Milliseconds"}]
+			}]
+		},
+		"FunctionName": "orders",
+		"requestId": "request-1",
+		"Orders": [1, 2],
+		"Duration": 12.5
+	}`, timestamp), lines[0])
+	assert.JSONEq(t, fmt.Sprintf(`{
+		"_aws": {
+			"Timestamp": %.0f,
+			"CloudWatchMetrics": [{
+				"Namespace": "Vesper",
+				"Dimensions": [["Dependency", "FunctionName"]],
+				"Metrics": [{"Name": "Latency", "Unit": "Milliseconds"}]
+			}]
+		},
+		"FunctionName": "orders",
+		"Dependency": "db",
+		"requestId": "request-1",
+		"Latency": 10
+	}`, timestamp), lines[1])
+}
+
+func TestEmitterLimits(t *testing.T) {
+	m := New()
+	for i := 0; i < 150; i++ {
+		m.Put(fmt.Sprintf("Metric%d", i), 1, UnitCount)
+		m.Put("Repeated", float64(i), UnitCount)
+	}
+	entries := NewEmitter("Vesper", nil).entries(m, time.Now())
+	assert.Len(t, entries, 2)
+
+	metricsIn := func(entry map[string]interface{}) []metricDefinition {
+		return entry["_aws"].(metadata).CloudWatchMetrics[0].Metrics
+	}
+	assert.Len(t, metricsIn(entries[0]), 100)
+	assert.Len(t, metricsIn(entries[1]), 52)
+	assert.Len(t, entries[0]["Repeated"], 100)
+	assert.Len(t, entries[1]["Repeated"], 50)
+	assert.Equal(t, float64(100), entries[1]["Repeated"].([]float64)[0])
+}
@@ -0,0 +1,146 @@
+// Package metrics records metrics during a Lambda invocation and emits them in the CloudWatch Embedded Metric
+// Format (EMF), so CloudWatch extracts them from the function logs without any network calls.
+package metrics
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Unit is the unit of a metric, as supported by CloudWatch
+type Unit string
+
+// Units supported by CloudWatch
+const (
+	UnitNone         Unit = "None"
+	UnitCount        Unit = "Count"
+	UnitPercent      Unit = "Percent"
+	UnitSeconds      Unit = "Seconds"
+	UnitMilliseconds Unit = "Milliseconds"
+	UnitMicroseconds Unit = "Microseconds"
+	UnitBytes        Unit = "Bytes"
+	UnitKilobytes    Unit = "Kilobytes"
+	UnitMegabytes    Unit = "Megabytes"
+	UnitCountSecond  Unit = "Count/Second"
+	UnitBytesSecond  Unit = "Bytes/Second"
+)
+
+type ctxKey string
+
+const ctxKeyMetrics = ctxKey("Metrics")
+
+// Metrics is the set of metrics recorded during an invocation, which is emitted by an Emitter.
+// The methods of Metrics are safe to call concurrently, and on a nil Metrics, in which case they do nothing.
+type Metrics struct {
+	mu         sync.Mutex
+	dimensions map[string]string
+	properties map[string]interface{}
+	series     []*series
+}
+
+// series are the values of a metric with a set of dimensions
+type series struct {
+	name       string
+	unit       Unit
+	dimensions map[string]string
+	values     []float64
+}
+
+// New creates an empty set of metrics
+func New() *Metrics {
+	return &Metrics{dimensions: map[string]string{}, properties: map[string]interface{}{}}
+}
+
+// Put records a value of a metric with the default dimensions
+func (m *Metrics) Put(name string, value float64, unit Unit) {
+	m.PutWithDimensions(name, value, unit, nil)
+}
+
+// PutWithDimensions records a value of a metric with dimensions in addition to the default dimensions, e.g. to
+// record the latency of each dependency as a separate metric
+func (m *Metrics) PutWithDimensions(name string, value float64, unit Unit, dimensions map[string]string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.series {
+		if s.name == name && equalDimensions(s.dimensions, dimensions) {
+			s.values = append(s.values, value)
+			return
+		}
+	}
+	s := &series{name: name, unit: unit, values: []float64{value}}
+	if len(dimensions) > 0 {
+		s.dimensions = make(map[string]string, len(dimensions))
+		for k, v := range dimensions {
+			s.dimensions[k] = v
+		}
+	}
+	m.series = append(m.series, s)
+}
+
+// SetDimension sets a default dimension, which applies to every metric
+func (m *Metrics) SetDimension(key, value string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dimensions[key] = value
+}
+
+// SetProperty sets a property, which is included in the log entry but is not a dimension, e.g. a request ID
+// which can be searched for with CloudWatch Logs Insights
+func (m *Metrics) SetProperty(key string, value interface{}) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.properties[key] = value
+}
+
+func equalDimensions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// dimensionKey identifies a set of dimension values
+func dimensionKey(dimensions map[string]string) string {
+	keys := sortedKeys(dimensions)
+	for i, k := range keys {
+		keys[i] = k + "=" + dimensions[k]
+	}
+	return strings.Join(keys, "\x00")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NewContext returns a context containing the metrics
+func NewContext(ctx context.Context, m *Metrics) context.Context {
+	return context.WithValue(ctx, ctxKeyMetrics, m)
+}
+
+// FromContext retrieves the metrics of the current invocation from a context, or nil if there are none.
+// The methods of Metrics can be called on nil, so the result can be used without checking it.
+func FromContext(ctx context.Context) *Metrics {
+	m, _ := ctx.Value(ctxKeyMetrics).(*Metrics)
+	return m
+}
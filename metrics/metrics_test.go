@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	var nilMetrics *Metrics
+	assert.NotPanics(t, func() {
+		nilMetrics.Put("Orders", 1, UnitCount)
+		nilMetrics.SetDimension("Service", "orders")
+		nilMetrics.SetProperty("orderId", "1")
+	})
+
+	m := New()
+	m.Put("Orders", 1, UnitCount)
+	m.Put("Orders", 2, UnitCount)
+	m.PutWithDimensions("Latency", 10, UnitMilliseconds, map[string]string{"Dependency": "db"})
+	m.PutWithDimensions("Latency", 20, UnitMilliseconds, map[string]string{"Dependency": "db"})
+	m.PutWithDimensions("Latency", 30, UnitMilliseconds, map[string]string{"Dependency": "api"})
+	assert.Len(t, m.series, 3)
+	assert.Equal(t, []float64{1, 2}, m.series[0].values)
+	assert.Equal(t, []float64{10, 20}, m.series[1].values)
+	assert.Equal(t, []float64{30}, m.series[2].values)
+
+	ctx := NewContext(context.Background(), m)
+	assert.Equal(t, m, FromContext(ctx))
+	assert.Nil(t, FromContext(context.Background()))
+}
@@ -0,0 +1,157 @@
+package vesper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/mefellows/vesper/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	decode := func(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+			entries = append(entries, entry)
+		}
+		buf.Reset()
+		return entries
+	}
+	metricNames := func(entry map[string]interface{}) []string {
+		var names []string
+		directive := entry["_aws"].(map[string]interface{})["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+		for _, m := range directive["Metrics"].([]interface{}) {
+			names = append(names, m.(map[string]interface{})["Name"].(string))
+		}
+		return names
+	}
+
+	t.Run("invocation metrics", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := func(ctx context.Context, in string) error {
+			metrics.FromContext(ctx).Put("Orders", 1, metrics.UnitCount)
+			if in == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		}
+		handler := New(h, CorrelationIDMiddleware()).With(WithMetrics(metrics.NewEmitter("Users", &buf))).buildHandler()
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "request-1"})
+
+		_, err := handler.Invoke(ctx, []byte(`"ok"`))
+		assert.NoError(t, err)
+		entries := decode(t, &buf)
+		assert.Len(t, entries, 3)
+		assert.Equal(t, []string{"Orders", "HandlerDuration", "Invocations", "Duration", "ColdStart", "Errors"}, metricNames(entries[0]))
+		assert.Equal(t, "request-1", entries[0]["requestId"])
+		assert.Equal(t, float64(1), entries[0]["ColdStart"])
+		assert.Equal(t, float64(0), entries[0]["Errors"])
+		assert.Equal(t, "Users", entries[0]["_aws"].(map[string]interface{})["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})["Namespace"])
+		for _, entry := range entries[1:] {
+			assert.Equal(t, []string{"MiddlewareDuration"}, metricNames(entry))
+		}
+		assert.Equal(t, "CorrelationIDMiddleware", entries[1]["Middleware"])
+		assert.Equal(t, "ParserMiddleware", entries[2]["Middleware"])
+
+		_, err = handler.Invoke(ctx, []byte(`"fail"`))
+		assert.EqualError(t, err, "failed")
+		entries = decode(t, &buf)
+		assert.Equal(t, float64(0), entries[0]["ColdStart"])
+		assert.Equal(t, float64(1), entries[0]["Errors"])
+	})
+
+	t.Run("middleware latency", func(t *testing.T) {
+		var buf bytes.Buffer
+		v := New(func() error { return nil }, CorrelationIDMiddleware(), TimeoutMiddleware(0)).With(WithMetrics(metrics.NewEmitter("Users", &buf)))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.NoError(t, err)
+
+		var middlewares []interface{}
+		for _, entry := range decode(t, &buf) {
+			if entry["Middleware"] != nil {
+				middlewares = append(middlewares, entry["Middleware"])
+				assert.Greater(t, entry["MiddlewareDuration"], float64(0))
+			}
+		}
+		assert.ElementsMatch(t, []interface{}{"ParserMiddleware", "CorrelationIDMiddleware", "TimeoutMiddleware"}, middlewares)
+	})
+
+	t.Run("middleware latency excludes the rest of the chain", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := func() error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}
+		slow := NamedMiddleware("slow", func(next LambdaFunc) LambdaFunc {
+			return func(ctx context.Context, in interface{}) (interface{}, error) {
+				time.Sleep(10 * time.Millisecond)
+				return next(ctx, in)
+			}
+		})
+		concurrent := NamedMiddleware("concurrent", func(next LambdaFunc) LambdaFunc {
+			return func(ctx context.Context, in interface{}) (interface{}, error) {
+				var wg sync.WaitGroup
+				for i := 0; i < 2; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, _ = next(ctx, in)
+					}()
+				}
+				wg.Wait()
+				return nil, nil
+			}
+		})
+		v := New(h, slow, concurrent).DisableAutoUnmarshal().With(WithMetrics(metrics.NewEmitter("Users", &buf)))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.NoError(t, err)
+
+		durations := map[interface{}]float64{}
+		for _, entry := range decode(t, &buf) {
+			if entry["Middleware"] != nil {
+				durations[entry["Middleware"]] = entry["MiddlewareDuration"].(float64)
+			} else {
+				// the handler is called twice by the concurrent middleware
+				durations["handler"] = entry["HandlerDuration"].([]interface{})[0].(float64)
+			}
+		}
+		assert.GreaterOrEqual(t, durations["slow"], float64(10))
+		assert.Less(t, durations["slow"], float64(50))
+		assert.Less(t, durations["concurrent"], float64(50))
+		assert.GreaterOrEqual(t, durations["handler"], float64(50))
+	})
+
+	t.Run("panics are counted as errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		v := NewWithOptions(func() error { panic("boom") }, WithMetrics(metrics.NewEmitter("Users", &buf)))
+		assert.Panics(t, func() {
+			_, _ = v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		})
+		assert.Equal(t, float64(1), decode(t, &buf)[0]["Errors"])
+	})
+
+	t.Run("trace ID property", func(t *testing.T) {
+		var buf bytes.Buffer
+		provider, recorder := newTestTracerProvider()
+		v := NewWithOptions(func() error { return nil }, WithMetrics(metrics.NewEmitter("Users", &buf)), WithTracerProvider(provider), WithMiddlewareSpans())
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.NoError(t, err)
+		spans := recorder.Ended()
+		root := spans[len(spans)-1]
+		assert.Equal(t, root.SpanContext().TraceID().String(), decode(t, &buf)[0]["traceId"])
+		var names []string
+		for _, s := range spans {
+			names = append(names, s.Name())
+		}
+		assert.Equal(t, []string{"handler", "ParserMiddleware", "invocation"}, names)
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // LambdaFunc is the long-form of the Lambda handler interface
@@ -24,25 +27,140 @@ func buildChain(f LambdaFunc, m ...Middleware) LambdaFunc {
 	return m[0](buildChain(f, m[1:]...))
 }
 
+// NamedMiddleware names a middleware, so its span and MiddlewareDuration metric can be told apart when the chain
+// is instrumented with WithMiddlewareSpans or WithMetrics. Middlewares which are not named are reported by their
+// position in the chain, e.g. "middleware 2". The middlewares of this package are named after their constructor,
+// e.g. TimeoutMiddleware.
+func NamedMiddleware(name string, m Middleware) Middleware {
+	return func(next LambdaFunc) LambdaFunc {
+		f := m(next)
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			if im := instrumentedMiddlewareFromContext(ctx); im != nil {
+				im.setName(name)
+			}
+			return f(ctx, in)
+		}
+	}
+}
+
+// instrumentedMiddleware is the state of an invocation of a middleware instrumented by wrapMiddlewares
+type instrumentedMiddleware struct {
+	mu     sync.Mutex
+	name   string
+	named  bool
+	start  time.Time
+	inNext time.Duration
+	// active is the number of calls to next in progress, which may be concurrent as for record handler middlewares,
+	// and nextStart when the first of them started
+	active    int
+	nextStart time.Time
+}
+
+// setName names the middleware, unless it has been named already by a NamedMiddleware wrapping this one
+func (im *instrumentedMiddleware) setName(name string) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	if !im.named {
+		im.name, im.named = name, true
+	}
+}
+
+func (im *instrumentedMiddleware) Name() string {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	return im.name
+}
+
+// Exclusive is the time spent in the middleware so far, excluding the time spent in the rest of the chain it calls
+func (im *instrumentedMiddleware) Exclusive() time.Duration {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	inNext := im.inNext
+	if im.active > 0 {
+		inNext += time.Since(im.nextStart) // e.g. abandoned by TimeoutMiddleware
+	}
+	return time.Since(im.start) - inNext
+}
+
+func (im *instrumentedMiddleware) enterNext() {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	if im.active == 0 {
+		im.nextStart = time.Now()
+	}
+	im.active++
+}
+
+func (im *instrumentedMiddleware) exitNext() {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	im.active--
+	if im.active == 0 {
+		im.inNext += time.Since(im.nextStart)
+	}
+}
+
+// wrapMiddlewares wraps the LambdaFunc returned by each middleware with wrap, which can retrieve the name of the
+// middleware, and the time spent in it, with instrumentedMiddlewareFromContext once the middleware has been invoked.
+func wrapMiddlewares(middlewares []Middleware, wrap func(f LambdaFunc) LambdaFunc) []Middleware {
+	wrapped := make([]Middleware, len(middlewares))
+	for i, m := range middlewares {
+		i, m := i, m
+		wrapped[i] = func(next LambdaFunc) LambdaFunc {
+			f := wrap(m(timeNext(next)))
+			return func(ctx context.Context, in interface{}) (interface{}, error) {
+				im := &instrumentedMiddleware{name: fmt.Sprintf("middleware %d", i), start: time.Now()}
+				return f(context.WithValue(ctx, ctxKeyInstrumentedMiddleware, im), in)
+			}
+		}
+	}
+	return wrapped
+}
+
+// timeNext records the time spent in next on the middleware calling it, so its own time can be told apart from
+// that of the rest of the chain
+func timeNext(next LambdaFunc) LambdaFunc {
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		im := instrumentedMiddlewareFromContext(ctx)
+		if im == nil {
+			return next(ctx, in)
+		}
+		im.enterNext()
+		defer im.exitNext()
+		return next(ctx, in)
+	}
+}
+
+// instrumentedMiddlewareFromContext returns the middleware being instrumented, or nil outside of the middlewares
+func instrumentedMiddlewareFromContext(ctx context.Context) *instrumentedMiddleware {
+	im, _ := ctx.Value(ctxKeyInstrumentedMiddleware).(*instrumentedMiddleware)
+	return im
+}
+
 // newMiddlewareWrapper takes the middleware chain, and converts it into
-// a Lambda-compatible interface
-func newMiddlewareWrapper(handlerInterface interface{}, middlewareChain LambdaFunc) lambdaHandler {
-	handlerType, err := handlerType(handlerInterface)
-	if err != nil {
+// a Lambda-compatible interface.
+// If logger is nil, nothing is logged.
+func newMiddlewareWrapper(handlerInterface interface{}, middlewareChain LambdaFunc, logger StructuredLogger) lambdaHandler {
+	if _, err := handlerType(handlerInterface); err != nil {
 		return errorHandler(err)
 	}
-	takesContext := handlerTakesContext(handlerType)
-	var tIn reflect.Type
-	if (!takesContext && handlerType.NumIn() == 1) || handlerType.NumIn() == 2 {
-		tIn = handlerType.In(handlerType.NumIn() - 1)
-	}
-	return func(ctx context.Context, payload []byte) (interface{}, error) {
-		log.Println("[newMiddlewareWrapper] wrapped function handler")
+	tIn := handlerInputType(handlerInterface)
+	var invoked atomic.Bool
+	return lambdaHandler{handler: func(ctx context.Context, payload []byte) (interface{}, error) {
+		l := logger
+		if l == nil {
+			l = noOpLogger{}
+		}
+		coldStart := !invoked.Swap(true)
+		l = invocationLogger(ctx, l, coldStart)
+		l.Debug("wrapped function handler", "middleware", "newMiddlewareWrapper")
 
+		ctx = context.WithValue(ctx, ctxKeyColdStart, coldStart)
+		ctx = context.WithValue(ctx, ctxKeyLogger, l)
 		ctx = context.WithValue(ctx, ctxKeyPayload, payload)
 		ctx = context.WithValue(ctx, ctxKeyTIn, tIn)
 		return middlewareChain(ctx, payload)
-	}
+	}}
 }
 
 // newTypedToUntypedWrapper takes a typed handler function
@@ -63,14 +181,10 @@ func newTypedToUntypedWrapper(handlerInterface interface{}) LambdaFunc {
 	}
 	takesContext := handlerTakesContext(handlerType)
 	handler := reflect.ValueOf(handlerInterface)
-
-	var tIn reflect.Type
-	if (!takesContext && handlerType.NumIn() == 1) || handlerType.NumIn() == 2 {
-		tIn = handlerType.In(handlerType.NumIn() - 1)
-	}
+	tIn := handlerInputType(handlerInterface)
 
 	return func(ctx context.Context, payload interface{}) (interface{}, error) {
-		log.Printf("[typedToUntypedWrapper] have payload: %+v \n", payload)
+		middlewareLogger(ctx, "typedToUntypedWrapper").Debug("calling handler", "payload", payload)
 
 		// construct arguments
 		var args []reflect.Value
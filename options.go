@@ -0,0 +1,93 @@
+package vesper
+
+import (
+	"github.com/mefellows/vesper/encoding"
+	"github.com/mefellows/vesper/metrics"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures a Vesper instance, and is given to NewWithOptions or applied with With.
+// Options only affect the instance they are given to, so instances with different configuration can be used concurrently.
+type Option func(*Vesper)
+
+// WithMiddleware adds middlewares onto the middleware chain, in the same way as Use
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(v *Vesper) {
+		v.middlewares = append(v.middlewares, middlewares...)
+	}
+}
+
+// WithLogger sets the logger of the instance, in the same way as UseLogger
+func WithLogger(l StructuredLogger) Option {
+	return func(v *Vesper) {
+		v.logger = l
+	}
+}
+
+// WithUnmarshaler sets the unmarshaler used to automatically unmarshal the payload into the handler input parameter
+// type, which defaults to json.Unmarshal
+func WithUnmarshaler(unmarshaler encoding.UnmarshalFunc) Option {
+	return func(v *Vesper) {
+		v.unmarshaler = unmarshaler
+	}
+}
+
+// WithoutAutoUnmarshal disables automatic unmarshaling of the payload, in the same way as DisableAutoUnmarshal
+func WithoutAutoUnmarshal() Option {
+	return func(v *Vesper) {
+		v.autoUnmarshal = false
+	}
+}
+
+// WithMarshaler sets the marshaler used to serialize the handler response, which defaults to json.Marshal
+func WithMarshaler(marshaler encoding.MarshalFunc) Option {
+	return func(v *Vesper) {
+		v.marshaler = marshaler
+	}
+}
+
+// WithPanicPolicy adds RecoverMiddleware with the given policy as the outermost middleware, so panics in the
+// handler or any middleware are logged and handled as per the policy
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(v *Vesper) {
+		v.recoverPanics = true
+		v.panicPolicy = policy
+	}
+}
+
+// WithTracerProvider adds TracingMiddleware as the outermost middleware, so each invocation is traced with an
+// OpenTelemetry span from the given TracerProvider, or the global one if it is nil
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(v *Vesper) {
+		v.tracing = true
+		v.tracer = provider
+	}
+}
+
+// WithTracePropagator sets the propagator TracingMiddleware extracts the trace context of the event with, instead of
+// DefaultTracePropagator, e.g. otel.GetTextMapPropagator() to use the global one
+func WithTracePropagator(propagator propagation.TextMapPropagator) Option {
+	return func(v *Vesper) {
+		v.propagator = propagator
+	}
+}
+
+// WithMiddlewareSpans adds a child span for each middleware in the chain, and one for the handler, to the
+// invocation span started by WithTracerProvider. Middleware spans are named by NamedMiddleware, e.g. TimeoutMiddleware,
+// or by their position in the chain.
+func WithMiddlewareSpans() Option {
+	return func(v *Vesper) {
+		v.traceChain = true
+	}
+}
+
+// WithMetrics adds MetricsMiddleware with the given emitter as the outermost middleware, after any tracing, so
+// metrics are emitted in the CloudWatch Embedded Metric Format for each invocation. The latency of each middleware
+// is recorded as MiddlewareDuration, excluding the time spent in the rest of the chain, and of the handler as
+// HandlerDuration.
+func WithMetrics(emitter *metrics.Emitter) Option {
+	return func(v *Vesper) {
+		v.metrics = emitter
+	}
+}
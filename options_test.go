@@ -0,0 +1,99 @@
+package vesper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOptions(t *testing.T) {
+	t.Run("middlewares are added in order", func(t *testing.T) {
+		h := func(ctx context.Context) error {
+			assertMiddlewareCalled(ctx, t, "m1", 0)
+			assertMiddlewareCalled(ctx, t, "m2", 1)
+			assertMiddlewareCalled(ctx, t, "m3", 2)
+			assertMiddlewareCalled(ctx, t, "m4", 3)
+			return nil
+		}
+		v := New(h, newTestMiddleware("m1")).With(WithMiddleware(newTestMiddleware("m2"), newTestMiddleware("m3"))).Use(newTestMiddleware("m4"))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.NoError(t, err)
+	})
+
+	t.Run("unmarshaler and marshaler", func(t *testing.T) {
+		h := func(ctx context.Context, in string) (string, error) {
+			return strings.ToUpper(in), nil
+		}
+		unmarshal := func(data []byte, v interface{}) error {
+			*(v.(*string)) = string(data)
+			return nil
+		}
+		marshal := func(v interface{}) ([]byte, error) {
+			return []byte(fmt.Sprint(v)), nil
+		}
+		v := NewWithOptions(h, WithUnmarshaler(unmarshal), WithMarshaler(marshal))
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`hello`))
+		assert.NoError(t, err)
+		assert.Equal(t, "HELLO", string(rsp))
+	})
+
+	t.Run("without auto unmarshal", func(t *testing.T) {
+		h := func(ctx context.Context, in []byte) (string, error) {
+			return string(in), nil
+		}
+		rsp, err := NewWithOptions(h, WithoutAutoUnmarshal()).buildHandler().Invoke(context.Background(), []byte(`"hello"`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"\"hello\""`, string(rsp))
+	})
+
+	t.Run("panic policy", func(t *testing.T) {
+		h := func(ctx context.Context) error {
+			panic("boom")
+		}
+		_, err := NewWithOptions(h, WithPanicPolicy(PanicPolicyReturnError)).buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.EqualError(t, err, "panic: boom")
+
+		assert.Panics(t, func() {
+			_, _ = New(h).buildHandler().Invoke(context.Background(), []byte(`{}`))
+		})
+	})
+}
+
+func TestInstancesAreIsolated(t *testing.T) {
+	var wg sync.WaitGroup
+	bufs := make([]bytes.Buffer, 2)
+	for i := range bufs {
+		i := i
+		h := func(ctx context.Context) error {
+			LoggerFromContext(ctx).Info("handled", "instance", i)
+			return nil
+		}
+		handler := NewWithOptions(h, WithLogger(NewJSONLogger(&bufs[i], slog.LevelInfo))).buildHandler()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_, err := handler.Invoke(context.Background(), []byte(`{}`))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range bufs {
+		lines := strings.Split(strings.TrimSpace(bufs[i].String()), "\n")
+		assert.Len(t, lines, 10)
+		for _, line := range lines {
+			var entry map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+			assert.Equal(t, float64(i), entry["instance"])
+		}
+	}
+}
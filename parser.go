@@ -12,7 +12,7 @@ import (
 
 // ParserMiddleware is a middleware which unmarshals the original payload to the handler input parameter type with the given unmarshaler.
 func ParserMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) LambdaFunc {
-	return func(next LambdaFunc) LambdaFunc {
+	return NamedMiddleware("ParserMiddleware", func(next LambdaFunc) LambdaFunc {
 		return func(ctx context.Context, in interface{}) (interface{}, error) {
 			if unmarshaler == nil {
 				return nil, errors.New("no unmarshaler was provided")
@@ -31,7 +31,7 @@ func ParserMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) Lambd
 			}
 			return next(ctx, nextIn)
 		}
-	}
+	})
 }
 
 // JSONParserMiddleware is a middleware which JSON unmarshals the original payload to the handler input parameter type.
@@ -0,0 +1,31 @@
+package vesper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// validateRecordsTIn checks that the handler input parameter can hold a batch of decoded records
+// from the given event source, rather than the raw event itself
+func validateRecordsTIn(middleware string, source string, tIn reflect.Type, eventType reflect.Type) error {
+	if tIn == eventType {
+		return fmt.Errorf("%s middleware should not be used if input parameter is %s", middleware, eventType.String())
+	}
+	if tIn.Kind() != reflect.Slice {
+		return fmt.Errorf("input parameter for %s event must be a slice", source)
+	}
+	return nil
+}
+
+// unmarshalEvent JSON unmarshals the raw payload into the given AWS event type
+func unmarshalEvent(source string, in interface{}, evt interface{}) error {
+	b, ok := in.([]byte)
+	if !ok {
+		return fmt.Errorf("expected []byte input but got %T", in)
+	}
+	if err := json.Unmarshal(b, evt); err != nil {
+		return fmt.Errorf("could not unmarshal %s event: %w", source, err)
+	}
+	return nil
+}
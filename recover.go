@@ -0,0 +1,82 @@
+package vesper
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error a panic is converted into by RecoverMiddleware
+type PanicError struct {
+	// Value is the value given to panic
+	Value interface{}
+	// Stack is the stack trace of the goroutine which panicked
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value given to panic if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// PanicPolicy decides what RecoverMiddleware does after logging a panic
+type PanicPolicy int
+
+const (
+	// PanicPolicyRethrow panics again with the original value, so the Lambda runtime reports a failed invocation
+	PanicPolicyRethrow PanicPolicy = iota
+	// PanicPolicyReturnError returns a *PanicError as the invocation error
+	PanicPolicyReturnError
+	// PanicPolicyHTTPResponse returns a 500 response for HTTP events, in the format of the event and without the panic
+	// details, and a *PanicError for other events
+	PanicPolicyHTTPResponse
+)
+
+// RecoverMiddleware is a middleware which recovers from panics in the rest of the chain, converting them into a
+// *PanicError carrying the panic value and stack trace. The panic is logged with the logger of the invocation,
+// then handled as per the policy. A *PanicError re-panicked from another goroutine, as TimeoutMiddleware does,
+// keeps its original stack trace. It is added as the outermost middleware by the WithPanicPolicy option.
+func RecoverMiddleware(policy PanicPolicy) func(LambdaFunc) LambdaFunc {
+	return NamedMiddleware("RecoverMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (res interface{}, err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				panicErr, ok := r.(*PanicError)
+				if !ok {
+					panicErr = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+				middlewareLogger(ctx, "RecoverMiddleware").Error("recovered from panic", "panic", fmt.Sprint(panicErr.Value), "stack", string(panicErr.Stack))
+
+				switch policy {
+				case PanicPolicyRethrow:
+					panic(r)
+				case PanicPolicyHTTPResponse:
+					if req, ok := httpRequestFromPayload(ctx); ok {
+						res, err = adaptHTTPResponse(req, ProblemHTTPErrorMapper(false)(ctx, panicErr)), nil
+						return
+					}
+				}
+				res, err = nil, panicErr
+			}()
+			return next(ctx, in)
+		}
+	})
+}
+
+// recoverError calls f, converting a panic into a *PanicError
+func recoverError(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return f()
+}
@@ -0,0 +1,79 @@
+package vesper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/mefellows/vesper/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	cause := errors.New("nil map")
+	h := func(ctx context.Context) error {
+		panic(cause)
+	}
+
+	t.Run("return error", func(t *testing.T) {
+		var buf bytes.Buffer
+		v := NewWithOptions(h, WithPanicPolicy(PanicPolicyReturnError), WithLogger(NewJSONLogger(&buf, slog.LevelInfo)))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		var panicErr *PanicError
+		assert.True(t, errors.As(err, &panicErr))
+		assert.Equal(t, cause, panicErr.Value)
+		assert.Contains(t, string(panicErr.Stack), "recover_test.go")
+		assert.True(t, errors.Is(err, cause))
+		assert.Contains(t, buf.String(), `"middleware":"RecoverMiddleware","panic":"nil map"`)
+	})
+
+	t.Run("rethrow", func(t *testing.T) {
+		var buf bytes.Buffer
+		v := NewWithOptions(h, WithPanicPolicy(PanicPolicyRethrow), WithLogger(NewJSONLogger(&buf, slog.LevelInfo)))
+		assert.PanicsWithValue(t, cause, func() {
+			_, _ = v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		})
+		assert.Contains(t, buf.String(), `"msg":"recovered from panic"`)
+	})
+
+	t.Run("HTTP response", func(t *testing.T) {
+		v := NewWithOptions(h, WithPanicPolicy(PanicPolicyHTTPResponse))
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{"version": "2.0", "rawPath": "/", "requestContext": {"http": {"method": "GET"}}}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"statusCode": 500,
+			"headers": {"Content-Type": "application/problem+json"},
+			"body": "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500}",
+			"isBase64Encoded": false
+		}`, string(rsp))
+
+		_, err = v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.EqualError(t, err, "panic: nil map")
+	})
+
+	t.Run("panics in middleware", func(t *testing.T) {
+		panicky := func(next LambdaFunc) LambdaFunc {
+			return func(ctx context.Context, in interface{}) (interface{}, error) {
+				panic("middleware")
+			}
+		}
+		v := New(func() error { return nil }, panicky).With(WithPanicPolicy(PanicPolicyReturnError))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.EqualError(t, err, "panic: middleware")
+	})
+
+	t.Run("outermost", func(t *testing.T) {
+		// the metrics middleware is inside the recover middleware, so a panic while emitting is recovered too
+		v := NewWithOptions(func() error { return nil }, WithPanicPolicy(PanicPolicyReturnError), WithMetrics(metrics.NewEmitter("Users", panickyWriter{})))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.EqualError(t, err, "panic: write")
+	})
+}
+
+type panickyWriter struct{}
+
+func (panickyWriter) Write([]byte) (int, error) {
+	panic("write")
+}
@@ -0,0 +1,234 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var httpRequestType = reflect.TypeOf(HTTPRequest{})
+
+// Router dispatches HTTP requests from API Gateway (REST and HTTP APIs) and ALBs to the handler registered
+// for the request method and path. Each route has its own handler and middleware chain.
+//
+// A Router is used as the handler of a Vesper instance, so middleware given to Vesper wraps every route:
+//
+//	r := vesper.NewRouter().
+//		Get("/users/{id}", GetUserHandler).
+//		Post("/users", CreateUserHandler, authMiddleware)
+//	vesper.New(r.Serve, vesper.WarmupMiddleware).Start()
+type Router struct {
+	routes []*route
+}
+
+type route struct {
+	method   string
+	segments []string
+	tIn      reflect.Type
+	handler  LambdaFunc
+}
+
+// NewRouter creates a new Router with no routes
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers a handler and route-scoped middlewares for the given method and path template.
+// Path templates may contain parameters such as /users/{id}, and a final greedy parameter such as /files/{path+}.
+// The parameter values are set in the PathParameters of the HTTPRequest.
+// A method of "*" matches any method.
+//
+// The handler may have any signature accepted by New. If its input parameter is an HTTPRequest the
+// request is passed as is, otherwise the request body is JSON unmarshaled into the input parameter.
+// Route middlewares receive the HTTPRequest as input, and the request is also available via HTTPRequestFromContext.
+// If the handler returns an HTTPResponse it is returned as is, otherwise the response is JSON marshaled
+// into the body of a 200 response.
+func (r *Router) Handle(method string, path string, handler interface{}, middlewares ...Middleware) *Router {
+	r.routes = append(r.routes, &route{
+		method:   strings.ToUpper(method),
+		segments: splitPath(path),
+		tIn:      handlerInputType(handler),
+		handler:  buildChain(bindHTTPRequest(newTypedToUntypedWrapper(handler)), middlewares...),
+	})
+	return r
+}
+
+// Get registers a handler for GET requests to the path template. See Handle.
+func (r *Router) Get(path string, handler interface{}, middlewares ...Middleware) *Router {
+	return r.Handle(http.MethodGet, path, handler, middlewares...)
+}
+
+// Post registers a handler for POST requests to the path template. See Handle.
+func (r *Router) Post(path string, handler interface{}, middlewares ...Middleware) *Router {
+	return r.Handle(http.MethodPost, path, handler, middlewares...)
+}
+
+// Put registers a handler for PUT requests to the path template. See Handle.
+func (r *Router) Put(path string, handler interface{}, middlewares ...Middleware) *Router {
+	return r.Handle(http.MethodPut, path, handler, middlewares...)
+}
+
+// Patch registers a handler for PATCH requests to the path template. See Handle.
+func (r *Router) Patch(path string, handler interface{}, middlewares ...Middleware) *Router {
+	return r.Handle(http.MethodPatch, path, handler, middlewares...)
+}
+
+// Delete registers a handler for DELETE requests to the path template. See Handle.
+func (r *Router) Delete(path string, handler interface{}, middlewares ...Middleware) *Router {
+	return r.Handle(http.MethodDelete, path, handler, middlewares...)
+}
+
+// Serve dispatches the request to the matching route, and is the handler to give to New.
+// Path parameters are URL decoded, whether the event has the decoded (REST APIs) or raw (HTTP APIs and ALBs) path.
+// A 404 response is returned if no route matches the path, and a 405 response if no route matches the method.
+func (r *Router) Serve(ctx context.Context, req HTTPRequest) (HTTPResponse, error) {
+	if req.Source == "" {
+		return HTTPResponse{}, errors.New("router expected an API Gateway or ALB HTTP event")
+	}
+
+	rt, params, allowed := r.match(req.Method, pathSegments(req))
+	if rt == nil {
+		if len(allowed) > 0 {
+			rsp, err := newJSONResponse(http.StatusMethodNotAllowed, map[string]string{"message": http.StatusText(http.StatusMethodNotAllowed)})
+			rsp.Headers["Allow"] = strings.Join(allowed, ", ")
+			return adaptHTTPResponse(req, rsp), err
+		}
+		rsp, err := newJSONResponse(http.StatusNotFound, map[string]string{"message": http.StatusText(http.StatusNotFound)})
+		return adaptHTTPResponse(req, rsp), err
+	}
+
+	if len(params) > 0 {
+		pathParams := make(map[string]string, len(req.PathParameters)+len(params))
+		for k, v := range req.PathParameters {
+			pathParams[k] = v
+		}
+		for k, v := range params {
+			pathParams[k] = v
+		}
+		req.PathParameters = pathParams
+	}
+
+	ctx = context.WithValue(ctx, ctxKeyTIn, rt.tIn)
+	ctx = context.WithValue(ctx, ctxKeyHTTPRequest, req)
+	res, err := rt.handler(ctx, req)
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+
+	var rsp HTTPResponse
+	switch v := res.(type) {
+	case HTTPResponse:
+		rsp = v
+	case *HTTPResponse:
+		rsp = *v
+	default:
+		if rsp, err = newJSONResponse(http.StatusOK, v); err != nil {
+			return HTTPResponse{}, fmt.Errorf("could not marshal response: %w", err)
+		}
+	}
+	return adaptHTTPResponse(req, rsp), nil
+}
+
+// match finds the route for the method and path segments, returning the path parameters of the route.
+// If no route matches, the methods allowed for the path are returned.
+func (r *Router) match(method string, segments []string) (*route, map[string]string, []string) {
+	var allowed []string
+	for _, rt := range r.routes {
+		params, ok := matchSegments(rt.segments, segments)
+		if !ok {
+			continue
+		}
+		if rt.method == "*" || rt.method == strings.ToUpper(method) {
+			return rt, params, nil
+		}
+		if !slices.Contains(allowed, rt.method) {
+			allowed = append(allowed, rt.method)
+		}
+	}
+	sort.Strings(allowed)
+	return nil, nil, allowed
+}
+
+func matchSegments(template []string, segments []string) (map[string]string, bool) {
+	params := map[string]string{}
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "+}") {
+			if i >= len(segments) {
+				return nil, false
+			}
+			params[t[1:len(t)-2]] = strings.Join(segments[i:], "/")
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			params[t[1:len(t)-1]] = segments[i]
+			continue
+		}
+		if t != segments[i] {
+			return nil, false
+		}
+	}
+	return params, len(template) == len(segments)
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// pathSegments splits the path of the request into URL decoded segments. API Gateway REST APIs give the decoded path,
+// while HTTP APIs and ALBs give the raw path, which is decoded after splitting so an encoded "/" stays in its segment.
+func pathSegments(req HTTPRequest) []string {
+	segments := splitPath(req.Path)
+	if req.Source == HTTPEventSourceAPIGateway {
+		return segments
+	}
+	for i, s := range segments {
+		if decoded, err := url.PathUnescape(s); err == nil {
+			segments[i] = decoded
+		}
+	}
+	return segments
+}
+
+// bindHTTPRequest converts the HTTPRequest given to a route into the route handler input parameter type
+func bindHTTPRequest(next LambdaFunc) LambdaFunc {
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		req, ok := in.(HTTPRequest)
+		if !ok {
+			return next(ctx, in) // a route middleware has already converted the request
+		}
+		tIn, _ := TInFromContext(ctx)
+		if tIn == nil || tIn == httpRequestType {
+			return next(ctx, in)
+		}
+		badRequest := func(err error) (interface{}, error) {
+			middlewareLogger(ctx, "Router").Info("could not unmarshal request body", "type", tIn.String(), "error", err)
+			return newJSONResponse(http.StatusBadRequest, map[string]string{"message": http.StatusText(http.StatusBadRequest)})
+		}
+		body, err := req.DecodedBody()
+		if err != nil {
+			return badRequest(err)
+		}
+		if len(body) == 0 {
+			return next(ctx, nil)
+		}
+		v, err := unmarshalToType(json.Unmarshal, tIn, body)
+		if err != nil {
+			return badRequest(err)
+		}
+		return next(ctx, v)
+	}
+}
@@ -0,0 +1,127 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter(t *testing.T) {
+	type user struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	getUser := func(ctx context.Context, req HTTPRequest) (user, error) {
+		return user{ID: req.PathParameters["id"]}, nil
+	}
+	createUser := func(ctx context.Context, u user) (HTTPResponse, error) {
+		return HTTPResponse{StatusCode: 201, Body: u.Name}, nil
+	}
+	getFile := func(ctx context.Context) (string, error) {
+		req, ok := HTTPRequestFromContext(ctx)
+		assert.True(t, ok)
+		return req.PathParameters["path"], nil
+	}
+	failing := func(ctx context.Context) error {
+		return errors.New("something happened")
+	}
+	var routeMiddlewareCalled bool
+	routeMiddleware := func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			routeMiddlewareCalled = true
+			assert.IsType(t, HTTPRequest{}, in)
+			return next(ctx, in)
+		}
+	}
+
+	router := NewRouter().
+		Get("/users/{id}", getUser).
+		Post("/users", createUser, routeMiddleware).
+		Get("/files/README.md", getFile).
+		Get("/files/{path+}", getFile).
+		Handle("*", "/fail", failing)
+	v := New(router.Serve)
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "path parameters",
+			payload:  `{"httpMethod": "GET", "path": "/users/123"}`,
+			expected: `{"statusCode": 200, "headers": {"Content-Type": "application/json"}, "multiValueHeaders": null, "body": "{\"id\":\"123\",\"name\":\"\"}"}`,
+		},
+		{
+			name:     "body is unmarshaled into handler input",
+			payload:  `{"version": "2.0", "rawPath": "/users", "requestContext": {"http": {"method": "POST"}}, "body": "{\"name\": \"myuser\"}"}`,
+			expected: `{"statusCode": 201, "body": "myuser", "isBase64Encoded": false}`,
+		},
+		{
+			name:     "invalid body",
+			payload:  `{"httpMethod": "POST", "path": "/users", "body": "not a user"}`,
+			expected: `{"statusCode": 400, "headers": {"Content-Type": "application/json"}, "multiValueHeaders": null, "body": "{\"message\":\"Bad Request\"}"}`,
+		},
+		{
+			name:     "greedy path parameter",
+			payload:  `{"httpMethod": "GET", "path": "/files/a/b.txt", "requestContext": {"elb": {"targetGroupArn": "arn"}}}`,
+			expected: `{"statusCode": 200, "statusDescription": "200 OK", "headers": {"Content-Type": "application/json"}, "multiValueHeaders": null, "body": "\"a/b.txt\"", "isBase64Encoded": false}`,
+		},
+		{
+			name:     "encoded path parameter",
+			payload:  `{"version": "2.0", "rawPath": "/users/john%20doe", "requestContext": {"http": {"method": "GET"}}}`,
+			expected: `{"statusCode": 200, "headers": {"Content-Type": "application/json"}, "body": "{\"id\":\"john doe\",\"name\":\"\"}", "isBase64Encoded": false}`,
+		},
+		{
+			name:     "decoded path parameter",
+			payload:  `{"httpMethod": "GET", "path": "/users/john doe"}`,
+			expected: `{"statusCode": 200, "headers": {"Content-Type": "application/json"}, "multiValueHeaders": null, "body": "{\"id\":\"john doe\",\"name\":\"\"}"}`,
+		},
+		{
+			name:     "encoded greedy path parameter",
+			payload:  `{"httpMethod": "GET", "path": "/files/a%2Fb/c%20d.txt", "requestContext": {"elb": {"targetGroupArn": "arn"}}}`,
+			expected: `{"statusCode": 200, "statusDescription": "200 OK", "headers": {"Content-Type": "application/json"}, "multiValueHeaders": null, "body": "\"a/b/c d.txt\"", "isBase64Encoded": false}`,
+		},
+		{
+			name:     "not found",
+			payload:  `{"httpMethod": "GET", "path": "/orders"}`,
+			expected: `{"statusCode": 404, "headers": {"Content-Type": "application/json"}, "multiValueHeaders": null, "body": "{\"message\":\"Not Found\"}"}`,
+		},
+		{
+			name:     "method not allowed",
+			payload:  `{"httpMethod": "DELETE", "path": "/users/123"}`,
+			expected: `{"statusCode": 405, "headers": {"Content-Type": "application/json", "Allow": "GET"}, "multiValueHeaders": null, "body": "{\"message\":\"Method Not Allowed\"}"}`,
+		},
+		{
+			name:     "allowed methods are not repeated",
+			payload:  `{"httpMethod": "DELETE", "path": "/files/README.md"}`,
+			expected: `{"statusCode": 405, "headers": {"Content-Type": "application/json", "Allow": "GET"}, "multiValueHeaders": null, "body": "{\"message\":\"Method Not Allowed\"}"}`,
+		},
+		{
+			name:    "error from handler",
+			payload: `{"httpMethod": "PATCH", "path": "/fail"}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an HTTP event",
+			payload: `{}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rsp, err := v.buildHandler().Invoke(context.Background(), []byte(tt.payload))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(rsp))
+		})
+	}
+	assert.True(t, routeMiddlewareCalled)
+}
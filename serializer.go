@@ -0,0 +1,234 @@
+package vesper
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mefellows/vesper/encoding"
+	"gopkg.in/yaml.v2"
+)
+
+// ResponseFormat is a format which ResponseSerializerMiddleware can serialize responses into
+type ResponseFormat struct {
+	// ContentType is the media type of the format, which is set as the Content-Type of the response
+	ContentType string
+	// Aliases are other media types which are accepted for the format, e.g. text/xml for application/xml
+	Aliases []string
+	// Marshal serializes the response
+	Marshal encoding.MarshalFunc
+}
+
+var (
+	// JSONResponseFormat serializes responses as JSON
+	JSONResponseFormat = ResponseFormat{ContentType: "application/json", Marshal: json.Marshal}
+	// XMLResponseFormat serializes responses as XML
+	XMLResponseFormat = ResponseFormat{ContentType: "application/xml", Aliases: []string{"text/xml"}, Marshal: xml.Marshal}
+	// YAMLResponseFormat serializes responses as YAML
+	YAMLResponseFormat = ResponseFormat{ContentType: "application/yaml", Aliases: []string{"application/x-yaml", "text/yaml"}, Marshal: yaml.Marshal}
+	// MsgPackResponseFormat serializes responses as MessagePack
+	MsgPackResponseFormat = ResponseFormat{ContentType: "application/msgpack", Aliases: []string{"application/x-msgpack"}, Marshal: encoding.MarshalMsgPack}
+	// ProtobufResponseFormat serializes responses which are a proto.Message as protocol buffers
+	ProtobufResponseFormat = ResponseFormat{ContentType: "application/x-protobuf", Aliases: []string{"application/protobuf"}, Marshal: encoding.MarshalProtobuf}
+)
+
+// ResponseSerializerMiddleware is a middleware which serializes the handler response of HTTP events into the format
+// requested by the Accept header, returning an HTTPResponse with the matching Content-Type.
+// The first format is used if there is no Accept header, and a 406 response is returned without invoking the handler
+// if no format is acceptable.
+// If no formats are given, JSON, XML, YAML and MessagePack are supported, with JSON the default.
+// Binary formats are base64 encoded in the response. Handlers which return a proto.Message can also be serialized
+// as protocol buffers by giving ProtobufResponseFormat, and other formats are added with a ResponseFormat:
+//
+//	vesper.ResponseSerializerMiddleware(vesper.JSONResponseFormat, vesper.ProtobufResponseFormat)
+//
+// Every response to a negotiated request has a Vary: Accept header, including the 406 response and HTTPResponses
+// returned by the handler. Errors are returned as is.
+// Responses which are already an HTTPResponse, and responses to events which are not HTTP events, are returned as is.
+// To change the serialization of those, see WithMarshaler. As a Router converts handler responses into an HTTPResponse,
+// the middleware must be given to routes rather than to New when used with a Router.
+func ResponseSerializerMiddleware(formats ...ResponseFormat) func(LambdaFunc) LambdaFunc {
+	if len(formats) == 0 {
+		formats = []ResponseFormat{JSONResponseFormat, XMLResponseFormat, YAMLResponseFormat, MsgPackResponseFormat}
+	}
+	return NamedMiddleware("ResponseSerializerMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			req, ok := httpRequestFromPayload(ctx)
+			if !ok {
+				return next(ctx, in) // continue as there is no Accept header to negotiate with anyway.
+			}
+			// negotiate before invoking the handler, so a request which cannot be answered has no side effects
+			format, ok := negotiateFormat(req.Header("Accept"), formats)
+			if !ok {
+				middlewareLogger(ctx, "ResponseSerializerMiddleware").Info("no acceptable response format", "accept", req.Header("Accept"))
+				rsp, err := newJSONResponse(http.StatusNotAcceptable, map[string]string{"message": http.StatusText(http.StatusNotAcceptable)})
+				return adaptHTTPResponse(req, varyAccept(rsp)), err
+			}
+
+			res, err := next(ctx, in)
+			if err != nil {
+				return nil, err
+			}
+			switch rsp := res.(type) {
+			case HTTPResponse:
+				return varyAccept(rsp), nil
+			case *HTTPResponse:
+				if rsp == nil {
+					return res, nil
+				}
+				varied := varyAccept(*rsp)
+				return &varied, nil
+			}
+
+			b, err := format.Marshal(res)
+			if err != nil {
+				return nil, fmt.Errorf("could not marshal response as %s: %w", format.ContentType, err)
+			}
+			rsp := HTTPResponse{
+				StatusCode: http.StatusOK,
+				Headers:    map[string]string{"Content-Type": format.ContentType, "Vary": "Accept"},
+			}
+			if isTextContentType(format.ContentType) {
+				rsp.Body = string(b)
+			} else {
+				rsp.Body = base64.StdEncoding.EncodeToString(b)
+				rsp.IsBase64Encoded = true
+			}
+			return adaptHTTPResponse(req, rsp), nil
+		}
+	})
+}
+
+// varyAccept adds Accept to the Vary header of the response, so caches store a response per Accept header
+func varyAccept(rsp HTTPResponse) HTTPResponse {
+	varies := func(v string) bool {
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field == "*" || strings.EqualFold(field, "Accept") {
+				return true
+			}
+		}
+		return false
+	}
+	for k, vs := range rsp.MultiValueHeaders {
+		if strings.EqualFold(k, "Vary") {
+			for _, v := range vs {
+				if varies(v) {
+					return rsp
+				}
+			}
+			headers := make(map[string][]string, len(rsp.MultiValueHeaders))
+			for k, vs := range rsp.MultiValueHeaders {
+				headers[k] = vs
+			}
+			headers[k] = append(append([]string{}, vs...), "Accept")
+			rsp.MultiValueHeaders = headers
+			return rsp
+		}
+	}
+	headers := copyHeaders(rsp.Headers)
+	rsp.Headers = headers
+	for k, v := range headers {
+		if strings.EqualFold(k, "Vary") {
+			if !varies(v) {
+				headers[k] = v + ", Accept"
+			}
+			return rsp
+		}
+	}
+	headers["Vary"] = "Accept"
+	return rsp
+}
+
+// httpRequestFromPayload retrieves the HTTPRequest of a route, or unmarshals it from the original payload
+func httpRequestFromPayload(ctx context.Context) (HTTPRequest, bool) {
+	if req, ok := HTTPRequestFromContext(ctx); ok {
+		return req, true
+	}
+	payload, ok := PayloadFromContext(ctx)
+	if !ok {
+		return HTTPRequest{}, false
+	}
+	var req HTTPRequest
+	if err := json.Unmarshal(payload, &req); err != nil || req.Source == "" {
+		return HTTPRequest{}, false
+	}
+	return req, true
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiateFormat picks the format most preferred by an Accept header, as per RFC 7231
+func negotiateFormat(accept string, formats []ResponseFormat) (ResponseFormat, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return formats[0], true
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	// prefer higher quality, then more specific ranges, then the order given
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+
+	for _, r := range ranges {
+		for _, f := range formats {
+			if f.matches(r.mediaType) {
+				return f, true
+			}
+		}
+	}
+	return ResponseFormat{}, false
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// matches reports whether the format is acceptable for a media range such as application/json, application/* or */*
+func (f ResponseFormat) matches(mediaRange string) bool {
+	if mediaRange == "*/*" {
+		return true
+	}
+	for _, t := range append([]string{f.ContentType}, f.Aliases...) {
+		if strings.EqualFold(t, mediaRange) {
+			return true
+		}
+		if strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(t, strings.TrimSuffix(mediaRange, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package vesper
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type serializedUser struct {
+	Name string `json:"name" yaml:"name" xml:"name"`
+}
+
+func TestResponseSerializerMiddleware(t *testing.T) {
+	h := func(ctx context.Context) (serializedUser, error) {
+		return serializedUser{Name: "Bob"}, nil
+	}
+
+	tests := []struct {
+		name        string
+		accept      string
+		status      int
+		contentType string
+		body        string
+		base64      bool
+	}{
+		{name: "no accept header", status: 200, contentType: "application/json", body: `{"name":"Bob"}`},
+		{name: "any", accept: "*/*", status: 200, contentType: "application/json", body: `{"name":"Bob"}`},
+		{name: "xml", accept: "text/xml", status: 200, contentType: "application/xml", body: `<serializedUser><name>Bob</name></serializedUser>`},
+		{name: "quality", accept: "application/json;q=0.5, application/yaml", status: 200, contentType: "application/yaml", body: "name: Bob\n"},
+		{name: "specificity", accept: "*/*, application/msgpack", status: 200, contentType: "application/msgpack", body: base64.StdEncoding.EncodeToString([]byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xa3, 'B', 'o', 'b'}), base64: true},
+		{name: "wildcard subtype", accept: "application/*", status: 200, contentType: "application/json", body: `{"name":"Bob"}`},
+		{name: "not acceptable", accept: "image/png, application/json;q=0", status: 406, contentType: "application/json", body: `{"message":"Not Acceptable"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(h, ResponseSerializerMiddleware())
+			payload, _ := json.Marshal(map[string]interface{}{"httpMethod": "GET", "path": "/", "headers": map[string]string{"Accept": tt.accept}})
+			rsp, err := v.buildHandler().Invoke(context.Background(), payload)
+			assert.NoError(t, err)
+
+			var proxy struct {
+				StatusCode      int               `json:"statusCode"`
+				Headers         map[string]string `json:"headers"`
+				Body            string            `json:"body"`
+				IsBase64Encoded bool              `json:"isBase64Encoded"`
+			}
+			assert.NoError(t, json.Unmarshal(rsp, &proxy))
+			assert.Equal(t, tt.status, proxy.StatusCode)
+			assert.Equal(t, tt.contentType, proxy.Headers["Content-Type"])
+			assert.Equal(t, "Accept", proxy.Headers["Vary"])
+			assert.Equal(t, tt.body, proxy.Body)
+			assert.Equal(t, tt.base64, proxy.IsBase64Encoded)
+		})
+	}
+
+	t.Run("not acceptable does not invoke the handler", func(t *testing.T) {
+		called := false
+		v := New(func(ctx context.Context) (serializedUser, error) {
+			called = true
+			return serializedUser{}, nil
+		}, ResponseSerializerMiddleware())
+		payload, _ := json.Marshal(map[string]interface{}{"httpMethod": "POST", "path": "/", "headers": map[string]string{"Accept": "image/png"}})
+		rsp, err := v.buildHandler().Invoke(context.Background(), payload)
+		assert.NoError(t, err)
+		assert.Contains(t, string(rsp), `"statusCode":406`)
+		assert.False(t, called)
+	})
+
+	t.Run("HTTP responses vary by Accept", func(t *testing.T) {
+		for _, tt := range []struct {
+			rsp  interface{}
+			vary string
+		}{
+			{HTTPResponse{StatusCode: 404}, `"Vary":"Accept"`},
+			{&HTTPResponse{StatusCode: 404, Headers: map[string]string{"Vary": "Origin"}}, `"Vary":"Origin, Accept"`},
+			{HTTPResponse{StatusCode: 404, Headers: map[string]string{"vary": "*"}}, `"vary":"*"`},
+		} {
+			v := New(func(ctx context.Context) (interface{}, error) {
+				return tt.rsp, nil
+			}, ResponseSerializerMiddleware())
+			rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{"httpMethod": "GET", "path": "/"}`))
+			assert.NoError(t, err)
+			assert.Contains(t, string(rsp), tt.vary)
+		}
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		v := New(func(ctx context.Context) (*wrapperspb.StringValue, error) {
+			return wrapperspb.String("Bob"), nil
+		}, ResponseSerializerMiddleware(JSONResponseFormat, ProtobufResponseFormat))
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{"httpMethod": "GET", "path": "/", "headers": {"Accept": "application/protobuf"}}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"statusCode": 200, "headers": {"Content-Type": "application/x-protobuf", "Vary": "Accept"}, "multiValueHeaders": null, "body": "CgNCb2I=", "isBase64Encoded": true}`, string(rsp))
+	})
+
+	t.Run("not an HTTP event", func(t *testing.T) {
+		v := New(h, ResponseSerializerMiddleware())
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name":"Bob"}`, string(rsp))
+	})
+
+	t.Run("route", func(t *testing.T) {
+		r := NewRouter().Get("/users", h, ResponseSerializerMiddleware(YAMLResponseFormat))
+		rsp, err := New(r.Serve).buildHandler().Invoke(context.Background(), []byte(`{"version": "2.0", "rawPath": "/users", "requestContext": {"http": {"method": "GET"}}}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"statusCode": 200, "headers": {"Content-Type": "application/yaml", "Vary": "Accept"}, "body": "name: Bob\n", "isBase64Encoded": false}`, string(rsp))
+	})
+}
@@ -0,0 +1,98 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper/encoding"
+)
+
+// SNSParserMiddleware transforms SNS event record messages into the handler input parameter type using the given unmarshaler.
+// The handler input parameter must be a slice.
+func SNSParserMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) LambdaFunc {
+	validateTIn := func(tIn reflect.Type) error {
+		return validateRecordsTIn("SNSParserMiddleware", "SNS", tIn, reflect.TypeOf(events.SNSEvent{}))
+	}
+
+	unmarshalSNSEvent := func(in interface{}) (events.SNSEvent, error) {
+		evt := events.SNSEvent{}
+		if err := unmarshalEvent("SNS", in, &evt); err != nil {
+			return events.SNSEvent{}, err
+		}
+		return evt, nil
+	}
+
+	unmarshalRecords := func(tIn reflect.Type, evt events.SNSEvent) (reflect.Value, error) {
+		tIns := reflect.MakeSlice(tIn, 0, len(evt.Records))
+		for _, r := range evt.Records {
+			msg, err := unmarshalToType(unmarshaler, tIn.Elem(), []byte(r.SNS.Message))
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("could not unmarshal SNS message for ID %s: %w", r.SNS.MessageID, err)
+			}
+			tIns = reflect.Append(tIns, reflect.ValueOf(msg))
+		}
+		return tIns, nil
+	}
+
+	return NamedMiddleware("SNSParserMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			if unmarshaler == nil {
+				return nil, errors.New("no unmarshaler was provided")
+			}
+			tIn, ok := TInFromContext(ctx)
+			if !ok {
+				return next(ctx, in) // continue as there is no TIn to parse anyway.
+			}
+			if err := validateTIn(tIn); err != nil {
+				return nil, err
+			}
+			evt, err := unmarshalSNSEvent(in)
+			if err != nil {
+				return nil, err
+			}
+			tIns, err := unmarshalRecords(tIn, evt)
+			if err != nil {
+				return nil, err
+			}
+			return next(ctx, tIns.Interface())
+		}
+	})
+}
+
+// JSONSNSParserMiddleware transforms SNS event record messages into the handler input parameter type using a JSON unmarshaler.
+// The handler input parameter must be a slice.
+func JSONSNSParserMiddleware() func(LambdaFunc) LambdaFunc {
+	return SNSParserMiddleware(json.Unmarshal)
+}
+
+// SNSEnvelopeUnmarshaler wraps an unmarshaler so that it first extracts the message from an SNS notification envelope.
+// This is useful when an SQS queue is subscribed to an SNS topic without raw message delivery, as each
+// SQS message body is then the JSON notification sent by SNS rather than the original message, e.g.
+//
+//	vesper.SQSParserMiddleware(vesper.SNSEnvelopeUnmarshaler(json.Unmarshal))
+func SNSEnvelopeUnmarshaler(unmarshaler encoding.UnmarshalFunc) encoding.UnmarshalFunc {
+	return func(data []byte, v interface{}) error {
+		if unmarshaler == nil {
+			return errors.New("no unmarshaler was provided")
+		}
+		envelope := events.SNSEntity{}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return fmt.Errorf("could not unmarshal SNS envelope: %w", err)
+		}
+		if envelope.Type != "Notification" {
+			return fmt.Errorf("expected SNS envelope of type Notification but got '%s'", envelope.Type)
+		}
+		return unmarshaler([]byte(envelope.Message), v)
+	}
+}
+
+// JSONSQSSNSParserMiddleware transforms SQS event records, whose bodies are SNS notification envelopes,
+// into the handler input parameter type using a JSON unmarshaler.
+// The handler input parameter must be a slice.
+func JSONSQSSNSParserMiddleware() func(LambdaFunc) LambdaFunc {
+	return SQSParserMiddleware(SNSEnvelopeUnmarshaler(json.Unmarshal))
+}
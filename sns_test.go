@@ -0,0 +1,127 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSNSParser(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	t.Run("no unmarshaler", func(t *testing.T) {
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := SNSParserMiddleware(nil)(nextFunc)
+		_, err := middleware(context.Background(), []byte("{}"))
+		assert.Error(t, err)
+	})
+
+	t.Run("TIn is of type events.SNSEvent", func(t *testing.T) {
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := JSONSNSParserMiddleware()(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf(events.SNSEvent{}))
+		_, err := middleware(ctx, []byte("{}"))
+		assert.Error(t, err)
+	})
+
+	t.Run("message cannot be unmarshalled", func(t *testing.T) {
+		request := `{"Records": [{"Sns": {"MessageId": "1", "Message": "not a user"}}]}`
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := JSONSNSParserMiddleware()(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf([]user{}))
+		_, err := middleware(ctx, []byte(request))
+		assert.Error(t, err)
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		request := `
+{
+  "Records": [
+    {
+      "EventSource": "aws:sns",
+      "Sns": {
+        "MessageId": "95df01b4-ee98-5cb9-9903-4c221d41eb5e",
+        "Message": "{\"name\": \"myuser\", \"age\": 20}"
+      }
+    }
+  ]
+}
+`
+		called := false
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			called = true
+			assert.Equal(t, []user{{Name: "myuser", Age: 20}}, in)
+			return nil, nil
+		}
+		middleware := SNSParserMiddleware(json.Unmarshal)(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf([]user{}))
+		_, err := middleware(ctx, []byte(request))
+		assert.NoError(t, err)
+		assert.True(t, called)
+	})
+}
+
+func TestSNSEnvelopeUnmarshaler(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	t.Run("SQS message body is an SNS notification", func(t *testing.T) {
+		request := `
+{
+  "Records": [
+    {
+      "messageId": "19dd0b57-b21e-4ac1-bd88-01bbb068cb78",
+      "body": "{\"Type\": \"Notification\", \"MessageId\": \"95df01b4-ee98-5cb9-9903-4c221d41eb5e\", \"TopicArn\": \"arn:aws:sns:us-east-1:123456789012:users\", \"Message\": \"{\\\"name\\\": \\\"myuser\\\", \\\"age\\\": 20}\"}"
+    }
+  ]
+}
+`
+		called := false
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			called = true
+			assert.Equal(t, []user{{Name: "myuser", Age: 20}}, in)
+			return nil, nil
+		}
+		middleware := JSONSQSSNSParserMiddleware()(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf([]user{}))
+		_, err := middleware(ctx, []byte(request))
+		assert.NoError(t, err)
+		assert.True(t, called)
+	})
+
+	t.Run("SQS message body is not an SNS notification", func(t *testing.T) {
+		request := `{"Records": [{"messageId": "1", "body": "{\"name\": \"myuser\", \"age\": 20}"}]}`
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := JSONSQSSNSParserMiddleware()(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf([]user{}))
+		_, err := middleware(ctx, []byte(request))
+		assert.Error(t, err)
+	})
+
+	t.Run("no unmarshaler", func(t *testing.T) {
+		var u user
+		err := SNSEnvelopeUnmarshaler(nil)([]byte(`{"Type": "Notification", "Message": "{}"}`), &u)
+		assert.Error(t, err)
+	})
+}
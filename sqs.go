@@ -15,23 +15,13 @@ import (
 // The handler input parameter must be a slice.
 func SQSParserMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) LambdaFunc {
 	validateTIn := func(tIn reflect.Type) error {
-		if tIn == reflect.TypeOf(events.SQSEvent{}) {
-			return errors.New("SQSParserMiddleware middleware should not be used if input parameter is events.SQSEvent")
-		}
-		if tIn.Kind() != reflect.Slice {
-			return errors.New("input parameter for SQS event must be a slice")
-		}
-		return nil
+		return validateRecordsTIn("SQSParserMiddleware", "SQS", tIn, reflect.TypeOf(events.SQSEvent{}))
 	}
 
 	unmarshalSQSEvent := func(in interface{}) (events.SQSEvent, error) {
-		b, ok := in.([]byte)
-		if !ok {
-			return events.SQSEvent{}, fmt.Errorf("expected []byte input but got %T", in)
-		}
 		evt := events.SQSEvent{}
-		if err := json.Unmarshal(b, &evt); err != nil {
-			return events.SQSEvent{}, fmt.Errorf("could not unmarshal SQS event: %w", err)
+		if err := unmarshalEvent("SQS", in, &evt); err != nil {
+			return events.SQSEvent{}, err
 		}
 		return evt, nil
 	}
@@ -39,16 +29,16 @@ func SQSParserMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) La
 	unmarshalRecords := func(tIn reflect.Type, evt events.SQSEvent) (reflect.Value, error) {
 		tIns := reflect.MakeSlice(tIn, 0, len(evt.Records))
 		for _, r := range evt.Records {
-			msgBody, err := unmarshalToType(unmarshaler, tIn.Elem(), []byte(r.Body))
+			msgBody, err := unmarshalSQSMessage(unmarshaler, tIn.Elem(), r)
 			if err != nil {
-				return reflect.Value{}, fmt.Errorf("could not unmarshal SQS message body for ID %s: %w", r.MessageId, err)
+				return reflect.Value{}, err
 			}
 			tIns = reflect.Append(tIns, reflect.ValueOf(msgBody))
 		}
 		return tIns, nil
 	}
 
-	return func(next LambdaFunc) LambdaFunc {
+	return NamedMiddleware("SQSParserMiddleware", func(next LambdaFunc) LambdaFunc {
 		return func(ctx context.Context, in interface{}) (interface{}, error) {
 			if unmarshaler == nil {
 				return nil, errors.New("no unmarshaler was provided")
@@ -70,7 +60,7 @@ func SQSParserMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) La
 			}
 			return next(ctx, tIns.Interface())
 		}
-	}
+	})
 }
 
 // JSONSQSParserMiddleware transforms SQS event records into the handler input parameter type using a JSON unmarshaler.
@@ -78,3 +68,65 @@ func SQSParserMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) La
 func JSONSQSParserMiddleware() func(LambdaFunc) LambdaFunc {
 	return SQSParserMiddleware(json.Unmarshal)
 }
+
+// SQSRecordHandlerMiddleware calls the handler once per SQS event record, unmarshaling the message body into the
+// handler input parameter type using the given unmarshaler. Records that fail to unmarshal, or for which the handler
+// returns an error, are reported by message ID in a BatchResponse so that only those messages are redriven.
+// The handler input parameter is a single record (e.g. func(context.Context, User) error), and the handler response is ignored.
+// The SQS message being processed can be retrieved with SQSMessageFromContext.
+func SQSRecordHandlerMiddleware(unmarshaler encoding.UnmarshalFunc) func(LambdaFunc) LambdaFunc {
+	return ConcurrentSQSRecordHandlerMiddleware(unmarshaler, 1)
+}
+
+// JSONSQSRecordHandlerMiddleware calls the handler once per SQS event record using a JSON unmarshaler.
+// See SQSRecordHandlerMiddleware.
+func JSONSQSRecordHandlerMiddleware() func(LambdaFunc) LambdaFunc {
+	return SQSRecordHandlerMiddleware(json.Unmarshal)
+}
+
+// ConcurrentSQSRecordHandlerMiddleware is the same as SQSRecordHandlerMiddleware, but processes up to concurrency
+// records at the same time. Messages from a FIFO queue sharing a MessageGroupId are processed in order, and once
+// one of them fails the remaining messages of the group are reported as failures without being processed.
+func ConcurrentSQSRecordHandlerMiddleware(unmarshaler encoding.UnmarshalFunc, concurrency int) func(LambdaFunc) LambdaFunc {
+	validateTIn := func(tIn reflect.Type) error {
+		if tIn == reflect.TypeOf(events.SQSEvent{}) {
+			return errors.New("SQSRecordHandlerMiddleware middleware should not be used if input parameter is events.SQSEvent")
+		}
+		return nil
+	}
+
+	parse := func(in interface{}) ([]batchRecord, error) {
+		if unmarshaler == nil {
+			return nil, errors.New("no unmarshaler was provided")
+		}
+		evt := events.SQSEvent{}
+		if err := unmarshalEvent("SQS", in, &evt); err != nil {
+			return nil, err
+		}
+		records := make([]batchRecord, 0, len(evt.Records))
+		for _, r := range evt.Records {
+			r := r
+			records = append(records, batchRecord{
+				id:    r.MessageId,
+				group: r.Attributes["MessageGroupId"],
+				withContext: func(ctx context.Context) context.Context {
+					return context.WithValue(ctx, ctxKeySQSMessage, r)
+				},
+				decode: func(tIn reflect.Type) (interface{}, error) {
+					return unmarshalSQSMessage(unmarshaler, tIn, r)
+				},
+			})
+		}
+		return records, nil
+	}
+
+	return recordHandlerMiddleware("SQSRecordHandlerMiddleware", concurrency, validateTIn, parse)
+}
+
+func unmarshalSQSMessage(unmarshaler encoding.UnmarshalFunc, t reflect.Type, r events.SQSMessage) (interface{}, error) {
+	msgBody, err := unmarshalToType(unmarshaler, t, []byte(r.Body))
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal SQS message body for ID %s: %w", r.MessageId, err)
+	}
+	return msgBody, nil
+}
@@ -3,6 +3,7 @@ package vesper
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -197,3 +198,93 @@ func TestSQSParser(t *testing.T) {
 		}
 	})
 }
+
+func TestSQSRecordHandler(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	t.Run("no unmarshaler", func(t *testing.T) {
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := SQSRecordHandlerMiddleware(nil)(nextFunc)
+		_, err := middleware(context.Background(), []byte("{}"))
+		assert.Error(t, err)
+	})
+
+	t.Run("TIn is of type events.SQSEvent", func(t *testing.T) {
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := JSONSQSRecordHandlerMiddleware()(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf(events.SQSEvent{}))
+		_, err := middleware(ctx, []byte(`{"Records": []}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("payload cannot be unmarshalled to SQSEvent", func(t *testing.T) {
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Errorf("unexpected call to next func")
+			return nil, nil
+		}
+		middleware := JSONSQSRecordHandlerMiddleware()(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf(user{}))
+		_, err := middleware(ctx, []byte("not an SQS event"))
+		assert.Error(t, err)
+	})
+
+	t.Run("reports failed records", func(t *testing.T) {
+		request := `
+{
+  "Records": [
+    {
+      "messageId": "19dd0b57-b21e-4ac1-bd88-01bbb068cb78",
+      "body": "{\"name\": \"myuser\", \"age\": 20}"
+    },
+    {
+      "messageId": "fa9c517a-1a5d-4109-b689-081ceee6edbb",
+      "body": "this is not a valid user"
+    },
+    {
+      "messageId": "2e1424d4-f796-459a-8184-9c92662be6da",
+      "body": "{\"name\": \"fail\", \"age\": 18}"
+    }
+  ]
+}
+`
+		var handled []string
+		h := func(ctx context.Context, u user) error {
+			msg, ok := SQSMessageFromContext(ctx)
+			assert.True(t, ok)
+			handled = append(handled, msg.MessageId)
+			if u.Name == "fail" {
+				return errors.New("something happened")
+			}
+			return nil
+		}
+		v := New(h).
+			DisableAutoUnmarshal().
+			Use(JSONSQSRecordHandlerMiddleware())
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(request))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"batchItemFailures": [{"itemIdentifier": "fa9c517a-1a5d-4109-b689-081ceee6edbb"}, {"itemIdentifier": "2e1424d4-f796-459a-8184-9c92662be6da"}]}`, string(rsp))
+		assert.Equal(t, []string{"19dd0b57-b21e-4ac1-bd88-01bbb068cb78", "2e1424d4-f796-459a-8184-9c92662be6da"}, handled)
+	})
+
+	t.Run("no failures", func(t *testing.T) {
+		request := `{"Records": [{"messageId": "1", "body": "{\"name\": \"myuser\", \"age\": 20}"}]}`
+		nextFunc := func(ctx context.Context, in interface{}) (interface{}, error) {
+			assert.Equal(t, user{Name: "myuser", Age: 20}, in)
+			return nil, nil
+		}
+		middleware := JSONSQSRecordHandlerMiddleware()(nextFunc)
+		ctx := context.WithValue(context.Background(), ctxKeyTIn, reflect.TypeOf(user{}))
+		rsp, err := middleware(ctx, []byte(request))
+		assert.NoError(t, err)
+		assert.Equal(t, BatchResponse{BatchItemFailures: []BatchItemFailure{}}, rsp)
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"os"
 
 	"github.com/mefellows/vesper"
@@ -29,7 +30,7 @@ var dummyMiddleware = func(f vesper.LambdaFunc) vesper.LambdaFunc {
 }
 
 func main() {
-	m := vesper.New(MyHandler, dummyMiddleware)
-	vesper.Logger(log.New(os.Stdout, "", log.LstdFlags))
+	m := vesper.New(MyHandler, dummyMiddleware).
+		UseLogger(vesper.NewJSONLogger(os.Stdout, slog.LevelDebug))
 	m.Start()
 }
@@ -0,0 +1,112 @@
+package vesper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// TimeoutError is the error returned by TimeoutMiddleware when the handler has not returned before its budget expires
+type TimeoutError struct {
+	// Elapsed is how long the handler had been running when it was abandoned
+	Elapsed time.Duration
+	// Remaining is the time which was left before the Lambda deadline when the handler was abandoned
+	Remaining time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("handler did not return after %s, %s before the invocation deadline", e.Elapsed.Round(time.Millisecond), e.Remaining.Round(time.Millisecond))
+}
+
+// Unwrap returns context.DeadlineExceeded, so the error can be checked with errors.Is
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// TimeoutMiddleware is a middleware which stops waiting for the rest of the chain a safety margin before the Lambda
+// deadline, leaving time to flush logs, metrics and responses before the function is killed.
+// The context given to the rest of the chain is cancelled margin before the deadline. Handlers which return once it
+// is cancelled have until half of the margin remains to do so, and their result is returned as is; this is how the
+// record handler middlewares report the records they did not get to as failures.
+// If the handler still has not returned the invocation is logged with what was in flight and a *TimeoutError is
+// returned, or a 504 problem details response for HTTP events. The handler goroutine is left running.
+// A margin of more than half of the time remaining is reduced to half of it, so the handler always has time to run.
+// Invocations without a deadline, and all invocations when margin is zero, are passed on as is. A negative margin
+// fails every invocation.
+func TimeoutMiddleware(margin time.Duration) func(LambdaFunc) LambdaFunc {
+	return NamedMiddleware("TimeoutMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			if margin < 0 {
+				return nil, fmt.Errorf("TimeoutMiddleware margin must not be negative, but is %s", margin)
+			}
+			deadline, ok := ctx.Deadline()
+			if !ok || margin == 0 {
+				return next(ctx, in) // continue as there is no deadline to keep to
+			}
+			start := time.Now()
+			margin := margin
+			if clamped := clampMargin(margin, time.Until(deadline)); clamped < margin {
+				middlewareLogger(ctx, "TimeoutMiddleware").Warn("margin is more than half of the time remaining, so it is reduced",
+					"margin", margin.String(), "remaining", time.Until(deadline).String(), "reducedMargin", clamped.String())
+				margin = clamped
+			}
+			budgetCtx, cancel := context.WithDeadline(ctx, deadline.Add(-margin))
+			defer cancel()
+
+			type result struct {
+				res   interface{}
+				err   error
+				panic *PanicError
+			}
+			results := make(chan result, 1)
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						results <- result{panic: &PanicError{Value: r, Stack: debug.Stack()}}
+					}
+				}()
+				res, err := next(budgetCtx, in)
+				results <- result{res: res, err: err}
+			}()
+
+			var r result
+			select {
+			case r = <-results:
+			case <-budgetCtx.Done():
+				grace := time.NewTimer(time.Until(deadline.Add(-margin / 2)))
+				defer grace.Stop()
+				select {
+				case r = <-results:
+				case <-grace.C:
+					return timedOut(ctx, in, start, deadline)
+				}
+			}
+			if r.panic != nil {
+				panic(r.panic) // panic in the invocation goroutine, so RecoverMiddleware or the runtime sees it
+			}
+			return r.res, r.err
+		}
+	})
+}
+
+func timedOut(ctx context.Context, in interface{}, start time.Time, deadline time.Time) (interface{}, error) {
+	timeoutErr := &TimeoutError{Elapsed: time.Since(start), Remaining: time.Until(deadline)}
+	logger := middlewareLogger(ctx, "TimeoutMiddleware")
+	args := []interface{}{"elapsed", timeoutErr.Elapsed.String(), "remaining", timeoutErr.Remaining.String(), "input", fmt.Sprintf("%T", in)}
+	if tIn, ok := TInFromContext(ctx); ok && tIn != nil {
+		args = append(args, "type", tIn.String())
+	}
+	req, isHTTP := httpRequestFromPayload(ctx)
+	if isHTTP {
+		args = append(args, "method", req.Method, "path", req.Path)
+	}
+	logger.Error("handler timed out", args...)
+
+	if isHTTP {
+		httpErr := &HTTPError{StatusCode: http.StatusGatewayTimeout, Message: "the request timed out", Err: timeoutErr}
+		return adaptHTTPResponse(req, ProblemHTTPErrorMapper(false)(ctx, httpErr)), nil
+	}
+	return nil, timeoutErr
+}
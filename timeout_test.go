@@ -0,0 +1,131 @@
+package vesper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	withDeadline := func(d time.Duration) (context.Context, context.CancelFunc) {
+		return context.WithDeadline(context.Background(), time.Now().Add(d))
+	}
+	block := func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	t.Run("handler returns in time", func(t *testing.T) {
+		ctx, cancel := withDeadline(time.Second)
+		defer cancel()
+		h := func(ctx context.Context) (string, error) {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.True(t, time.Until(deadline) < 900*time.Millisecond)
+			return "done", nil
+		}
+		rsp, err := New(h, TimeoutMiddleware(200*time.Millisecond)).buildHandler().Invoke(ctx, []byte(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"done"`, string(rsp))
+	})
+
+	t.Run("handler does not return", func(t *testing.T) {
+		ctx, cancel := withDeadline(100 * time.Millisecond)
+		defer cancel()
+		var buf bytes.Buffer
+		v := New(block, TimeoutMiddleware(50*time.Millisecond)).With(WithLogger(NewJSONLogger(&buf, slog.LevelInfo)))
+		start := time.Now()
+		_, err := v.buildHandler().Invoke(ctx, []byte(`{}`))
+		assert.True(t, time.Since(start) < 100*time.Millisecond)
+		var timeoutErr *TimeoutError
+		assert.True(t, errors.As(err, &timeoutErr))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.True(t, timeoutErr.Remaining > 0)
+		assert.Contains(t, buf.String(), `"msg":"handler timed out"`)
+		assert.Contains(t, buf.String(), `"input":"[]uint8"`)
+	})
+
+	t.Run("handler returns once cancelled", func(t *testing.T) {
+		ctx, cancel := withDeadline(100 * time.Millisecond)
+		defer cancel()
+		h := func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		_, err := New(h, TimeoutMiddleware(50*time.Millisecond)).buildHandler().Invoke(ctx, []byte(`{}`))
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("HTTP response", func(t *testing.T) {
+		ctx, cancel := withDeadline(100 * time.Millisecond)
+		defer cancel()
+		rsp, err := New(block, TimeoutMiddleware(50*time.Millisecond)).buildHandler().Invoke(ctx, []byte(`{"httpMethod": "GET", "path": "/"}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"statusCode": 504,
+			"headers": {"Content-Type": "application/problem+json"},
+			"multiValueHeaders": null,
+			"body": "{\"type\":\"about:blank\",\"title\":\"Gateway Timeout\",\"status\":504,\"detail\":\"the request timed out\"}"
+		}`, string(rsp))
+	})
+
+	t.Run("partial batch response", func(t *testing.T) {
+		ctx, cancel := withDeadline(100 * time.Millisecond)
+		defer cancel()
+		h := func(ctx context.Context, in string) error {
+			if in == "slow" {
+				time.Sleep(time.Second)
+			}
+			return nil
+		}
+		event := `{"Records": [{"messageId": "1", "body": "\"ok\""}, {"messageId": "2", "body": "\"slow\""}, {"messageId": "3", "body": "\"ok\""}]}`
+		rsp, err := New(h, TimeoutMiddleware(50*time.Millisecond), JSONSQSRecordHandlerMiddleware()).DisableAutoUnmarshal().buildHandler().Invoke(ctx, []byte(event))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"batchItemFailures": [{"itemIdentifier": "2"}, {"itemIdentifier": "3"}]}`, string(rsp))
+	})
+
+	t.Run("panics are raised in the invocation goroutine", func(t *testing.T) {
+		ctx, cancel := withDeadline(time.Second)
+		defer cancel()
+		h := func(ctx context.Context) error {
+			panic("boom")
+		}
+		v := New(h, TimeoutMiddleware(50*time.Millisecond)).With(WithPanicPolicy(PanicPolicyReturnError))
+		_, err := v.buildHandler().Invoke(ctx, []byte(`{}`))
+		var panicErr *PanicError
+		assert.True(t, errors.As(err, &panicErr))
+		assert.Equal(t, "boom", panicErr.Value)
+		assert.Contains(t, string(panicErr.Stack), "timeout_test.go")
+	})
+
+	t.Run("margin larger than the time remaining", func(t *testing.T) {
+		ctx, cancel := withDeadline(100 * time.Millisecond)
+		defer cancel()
+		var buf bytes.Buffer
+		h := func(ctx context.Context) (string, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "done", ctx.Err()
+		}
+		v := New(h, TimeoutMiddleware(time.Second)).With(WithLogger(NewJSONLogger(&buf, slog.LevelInfo)))
+		rsp, err := v.buildHandler().Invoke(ctx, []byte(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"done"`, string(rsp))
+		assert.Contains(t, buf.String(), `"msg":"margin is more than half of the time remaining, so it is reduced"`)
+	})
+
+	t.Run("negative margin", func(t *testing.T) {
+		_, err := New(func() error { return nil }, TimeoutMiddleware(-time.Second)).buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.EqualError(t, err, "TimeoutMiddleware margin must not be negative, but is -1s")
+	})
+
+	t.Run("no deadline", func(t *testing.T) {
+		rsp, err := New(func() (string, error) { return "done", nil }, TimeoutMiddleware(time.Second)).buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"done"`, string(rsp))
+	})
+}
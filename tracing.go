@@ -0,0 +1,254 @@
+package vesper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans started by Vesper
+const tracerName = "github.com/mefellows/vesper"
+
+// DefaultTracePropagator is the propagator used by TracingMiddleware when none is given. It extracts the W3C
+// traceparent header, falling back to the AWS X-Ray trace header.
+var DefaultTracePropagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(xray.Propagator{}, propagation.TraceContext{})
+
+// TracingMiddleware is a middleware which starts an OpenTelemetry span for each invocation, with the FaaS semantic
+// attributes: the request ID, cold start, trigger type, and the name and version of the function. Spans are
+// started from the given TracerProvider, or the global one (see otel.SetTracerProvider) if it is nil.
+//
+// The span continues the trace propagated by the event, which is extracted with the given propagator, or
+// DefaultTracePropagator if it is nil, from:
+//
+//   - HTTP events: the headers
+//   - SQS and SNS messages: the message attributes, and the AWSTraceHeader attribute of SQS messages as X-Amzn-Trace-Id
+//   - otherwise the X-Ray trace header given to the function by Lambda, as X-Amzn-Trace-Id
+//
+// For a batch of messages the span is linked to the trace of each message instead of continuing one of them.
+// The span is ended before the invocation returns, and the TracerProvider flushed if it has a ForceFlush method, as
+// the SDK TracerProvider does. Handlers start child spans from the context with trace.SpanFromContext or otel.Tracer.
+// It is added as the outermost middleware by the WithTracerProvider option.
+func TracingMiddleware(provider trace.TracerProvider, propagator propagation.TextMapPropagator) func(LambdaFunc) LambdaFunc {
+	if propagator == nil {
+		propagator = DefaultTracePropagator
+	}
+	return NamedMiddleware("TracingMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (res interface{}, err error) {
+			tp := provider
+			if tp == nil {
+				tp = otel.GetTracerProvider()
+			}
+			trigger, kind := faasTrigger(ctx)
+			parent, links := propagatedSpanContexts(ctx, propagator)
+			attributes := []attribute.KeyValue{semconv.CloudProviderAWS, trigger}
+			if coldStart, ok := ctx.Value(ctxKeyColdStart).(bool); ok {
+				attributes = append(attributes, semconv.FaaSColdstart(coldStart))
+			}
+			if lc, ok := lambdacontext.FromContext(ctx); ok {
+				attributes = append(attributes, semconv.FaaSInvocationID(lc.AwsRequestID), semconv.CloudResourceID(lc.InvokedFunctionArn))
+			}
+			name := "invocation"
+			if lambdacontext.FunctionName != "" {
+				name = lambdacontext.FunctionName
+				attributes = append(attributes, semconv.FaaSName(lambdacontext.FunctionName), semconv.FaaSVersion(lambdacontext.FunctionVersion))
+			}
+			if req, ok := httpRequestFromPayload(ctx); ok {
+				attributes = append(attributes, semconv.HTTPRequestMethodKey.String(req.Method), semconv.URLPath(req.Path))
+			}
+
+			options := []trace.SpanStartOption{trace.WithSpanKind(kind), trace.WithAttributes(attributes...), trace.WithLinks(links...)}
+			if parent.IsValid() {
+				ctx = trace.ContextWithRemoteSpanContext(ctx, parent)
+			} else if len(links) > 0 {
+				options = append(options, trace.WithNewRoot())
+			}
+			ctx, span := tp.Tracer(tracerName).Start(ctx, name, options...)
+			defer func() {
+				r := recover()
+				if r != nil {
+					recordSpanError(span, fmt.Errorf("panic: %v", r))
+				}
+				span.End()
+				if flusher, ok := tp.(interface{ ForceFlush(context.Context) error }); ok {
+					if flushErr := flusher.ForceFlush(ctx); flushErr != nil {
+						middlewareLogger(ctx, "TracingMiddleware").Warn("could not export spans", "error", flushErr)
+					}
+				}
+				if r != nil {
+					panic(r)
+				}
+			}()
+
+			res, err = next(ctx, in)
+			recordSpanError(span, err)
+			recordHTTPStatus(span, res)
+			return res, err
+		}
+	})
+}
+
+// recordSpanError records err on the span and sets its status to Error. A nil error is ignored.
+func recordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// recordHTTPStatus sets the status code of an HTTP response on the span, failing the span for server errors
+func recordHTTPStatus(span trace.Span, res interface{}) {
+	var status int
+	switch rsp := res.(type) {
+	case HTTPResponse:
+		status = rsp.StatusCode
+	case *HTTPResponse:
+		if rsp != nil {
+			status = rsp.StatusCode
+		}
+	default:
+		return
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
+
+// traceLambdaFunc wraps f in a child span of the span in the context, named after the middleware being
+// instrumented, or the given name for the handler
+func traceLambdaFunc(name string, f LambdaFunc) LambdaFunc {
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		parent := trace.SpanFromContext(ctx)
+		if !parent.SpanContext().IsValid() {
+			return f(ctx, in) // continue as there is no invocation span to add to
+		}
+		ctx, span := parent.TracerProvider().Tracer(tracerName).Start(ctx, name)
+		defer func() {
+			if im := instrumentedMiddlewareFromContext(ctx); im != nil {
+				span.SetName(im.Name())
+			}
+			span.End()
+		}()
+		res, err := f(ctx, in)
+		recordSpanError(span, err)
+		return res, err
+	}
+}
+
+// traceCarrier is a propagation.TextMapCarrier of headers or message attributes, matching keys ignoring case
+type traceCarrier map[string]string
+
+func (c traceCarrier) Get(key string) string {
+	return c[strings.ToLower(key)]
+}
+
+func (c traceCarrier) Set(key string, value string) {
+	c[strings.ToLower(key)] = value
+}
+
+func (c traceCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// tracedEvent is the subset of the supported events which carries trace context
+type tracedEvent struct {
+	Records []struct {
+		Attributes        map[string]string                     `json:"attributes"`
+		MessageAttributes map[string]events.SQSMessageAttribute `json:"messageAttributes"`
+		SNS               struct {
+			MessageAttributes map[string]snsMessageAttribute `json:"MessageAttributes"`
+		} `json:"Sns"`
+	} `json:"Records"`
+}
+
+// faasTrigger returns the OpenTelemetry faas.trigger of the event, and the matching span kind
+func faasTrigger(ctx context.Context) (attribute.KeyValue, trace.SpanKind) {
+	payload, _ := PayloadFromContext(ctx)
+	switch DetectEventSource(payload) {
+	case EventSourceAPIGateway, EventSourceAPIGatewayV2, EventSourceALB:
+		return semconv.FaaSTriggerHTTP, trace.SpanKindServer
+	case EventSourceSQS, EventSourceSNS, EventSourceKinesis, EventSourceEventBridge:
+		return semconv.FaaSTriggerPubsub, trace.SpanKindConsumer
+	case EventSourceDynamoDB, EventSourceS3:
+		return semconv.FaaSTriggerDatasource, trace.SpanKindConsumer
+	case EventSourceSchedule:
+		return semconv.FaaSTriggerTimer, trace.SpanKindServer
+	}
+	return semconv.FaaSTriggerOther, trace.SpanKindServer
+}
+
+// propagatedSpanContexts returns the span context to continue the trace of, or for a batch of messages links to
+// the span contexts of the messages
+func propagatedSpanContexts(ctx context.Context, propagator propagation.TextMapPropagator) (trace.SpanContext, []trace.Link) {
+	extract := func(carrier traceCarrier) trace.SpanContext {
+		return trace.SpanContextFromContext(propagator.Extract(context.Background(), carrier))
+	}
+
+	if req, ok := httpRequestFromPayload(ctx); ok {
+		carrier := traceCarrier{}
+		for k, v := range req.Headers {
+			carrier.Set(k, v)
+		}
+		for k, vs := range req.MultiValueHeaders {
+			if len(vs) > 0 {
+				carrier.Set(k, vs[0])
+			}
+		}
+		if sc := extract(carrier); sc.IsValid() {
+			return sc, nil
+		}
+	}
+
+	var event tracedEvent
+	if payload, ok := PayloadFromContext(ctx); ok {
+		_ = json.Unmarshal(payload, &event)
+	}
+	var links []trace.Link
+	for _, r := range event.Records {
+		carrier := traceCarrier{}
+		if header, ok := r.Attributes["AWSTraceHeader"]; ok {
+			carrier.Set("X-Amzn-Trace-Id", header)
+		}
+		for k, a := range r.SNS.MessageAttributes {
+			carrier.Set(k, a.Value)
+		}
+		for k, a := range r.MessageAttributes {
+			if a.StringValue != nil {
+				carrier.Set(k, *a.StringValue)
+			}
+		}
+		if sc := extract(carrier); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	if len(event.Records) == 1 && len(links) == 1 {
+		return links[0].SpanContext, nil
+	}
+	if len(links) > 0 {
+		return trace.SpanContext{}, links
+	}
+
+	if header, ok := ctx.Value("x-amzn-trace-id").(string); ok {
+		if sc := extract(traceCarrier{"x-amzn-trace-id": header}); sc.IsValid() {
+			return sc, nil
+		}
+	}
+	return trace.SpanContext{}, nil
+}
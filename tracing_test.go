@@ -0,0 +1,194 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracerProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+func spanAttributes(s sdktrace.ReadOnlySpan) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	for _, kv := range s.Attributes() {
+		attributes[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return attributes
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	lambdaCtx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "request-1", InvokedFunctionArn: "arn:aws:lambda:us-east-1:123456789012:function:users"})
+
+	t.Run("invocation span", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		h := func(ctx context.Context) error {
+			assert.True(t, trace.SpanFromContext(ctx).SpanContext().IsValid())
+			return nil
+		}
+		handler := NewWithOptions(h, WithTracerProvider(provider)).buildHandler()
+		for i := 0; i < 2; i++ {
+			_, err := handler.Invoke(lambdaCtx, []byte(`{"detail-type": "Scheduled Event", "source": "aws.events", "detail": {}}`))
+			assert.NoError(t, err)
+		}
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "invocation", spans[0].Name())
+		assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+		assert.Equal(t, map[string]interface{}{
+			"cloud.provider":     "aws",
+			"cloud.resource_id":  "arn:aws:lambda:us-east-1:123456789012:function:users",
+			"faas.coldstart":     true,
+			"faas.invocation_id": "request-1",
+			"faas.trigger":       "timer",
+		}, spanAttributes(spans[0]))
+		assert.Equal(t, false, spanAttributes(spans[1])["faas.coldstart"])
+		assert.NotEqual(t, spans[0].SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+	})
+
+	t.Run("global tracer provider", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		global := otel.GetTracerProvider()
+		otel.SetTracerProvider(provider)
+		defer otel.SetTracerProvider(global)
+
+		_, err := NewWithOptions(func() error { return nil }, WithTracerProvider(nil)).buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.NoError(t, err)
+		assert.Len(t, recorder.Ended(), 1)
+	})
+
+	t.Run("middleware spans", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		h := func(ctx context.Context, in string) error {
+			_, span := otel.GetTracerProvider().Tracer("test").Start(ctx, "database")
+			span.End()
+			_, span = trace.SpanFromContext(ctx).TracerProvider().Tracer("test").Start(ctx, "cache")
+			span.End()
+			return errors.New("failed")
+		}
+		anonymous := func(next LambdaFunc) LambdaFunc {
+			return next
+		}
+		v := New(h, CorrelationIDMiddleware(), anonymous, NamedMiddleware("auth", anonymous)).With(WithTracerProvider(provider), WithMiddlewareSpans())
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`"hello"`))
+		assert.EqualError(t, err, "failed")
+
+		spans := recorder.Ended()
+		var names []string
+		for _, s := range spans {
+			names = append(names, s.Name())
+		}
+		// the database span is started from the global no-op provider, so it is not recorded
+		assert.Equal(t, []string{"cache", "handler", "auth", "middleware 2", "CorrelationIDMiddleware", "ParserMiddleware", "invocation"}, names)
+		for i := 0; i < len(spans)-1; i++ {
+			assert.Equal(t, spans[i+1].SpanContext().SpanID(), spans[i].Parent().SpanID())
+			assert.Equal(t, spans[0].SpanContext().TraceID(), spans[i].SpanContext().TraceID())
+		}
+		assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "failed"}, spans[1].Status())
+		assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "failed"}, spans[6].Status())
+		assert.Equal(t, "other", spanAttributes(spans[6])["faas.trigger"])
+	})
+
+	t.Run("HTTP traceparent", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		r := NewRouter().Get("/users", func(ctx context.Context) (string, error) {
+			return "ok", nil
+		})
+		v := NewWithOptions(r.Serve, WithTracerProvider(provider))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"httpMethod": "GET", "path": "/users", "headers": {"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}`))
+		assert.NoError(t, err)
+
+		span := recorder.Ended()[0]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+		assert.True(t, span.Parent().IsRemote())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		attributes := spanAttributes(span)
+		assert.Equal(t, "http", attributes["faas.trigger"])
+		assert.Equal(t, "GET", attributes["http.request.method"])
+		assert.Equal(t, "/users", attributes["url.path"])
+		assert.Equal(t, int64(200), attributes["http.response.status_code"])
+	})
+
+	t.Run("HTTP X-Ray trace header", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		v := NewWithOptions(func() error { return nil }, WithTracerProvider(provider))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"httpMethod": "GET", "path": "/users", "headers": {"X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "5759e988bd862e3fe1be46a994272793", recorder.Ended()[0].SpanContext().TraceID().String())
+	})
+
+	t.Run("custom propagator", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		v := NewWithOptions(func() error { return nil }, WithTracerProvider(provider), WithTracePropagator(propagation.Baggage{}))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"httpMethod": "GET", "path": "/users", "headers": {"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}`))
+		assert.NoError(t, err)
+		assert.False(t, recorder.Ended()[0].Parent().IsValid())
+	})
+
+	t.Run("SQS message attributes", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		h := func(ctx context.Context) error {
+			return nil
+		}
+		v := NewWithOptions(h, WithTracerProvider(provider))
+		single := `{"Records": [{"eventSource": "aws:sqs", "messageAttributes": {"traceparent": {"stringValue": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "dataType": "String"}}}]}`
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(single))
+		assert.NoError(t, err)
+		span := recorder.Ended()[0]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, trace.SpanKindConsumer, span.SpanKind())
+		assert.Equal(t, "pubsub", spanAttributes(span)["faas.trigger"])
+
+		batch := `{"Records": [
+			{"eventSource": "aws:sqs", "messageAttributes": {"traceparent": {"stringValue": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "dataType": "String"}}},
+			{"eventSource": "aws:sqs", "attributes": {"AWSTraceHeader": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"}}
+		]}`
+		_, err = v.buildHandler().Invoke(context.Background(), []byte(batch))
+		assert.NoError(t, err)
+		span = recorder.Ended()[1]
+		assert.False(t, span.Parent().IsValid())
+		assert.Len(t, span.Links(), 2)
+		assert.Equal(t, "5759e988bd862e3fe1be46a994272793", span.Links()[1].SpanContext.TraceID().String())
+	})
+
+	t.Run("SNS message attributes", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		v := NewWithOptions(func() error { return nil }, WithTracerProvider(provider))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"Records": [{"EventSource": "aws:sns", "Sns": {"MessageAttributes": {"traceparent": {"Type": "String", "Value": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}}}]}`))
+		assert.NoError(t, err)
+		span := recorder.Ended()[0]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "pubsub", spanAttributes(span)["faas.trigger"])
+	})
+
+	t.Run("Lambda X-Ray trace header", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		v := NewWithOptions(func() error { return nil }, WithTracerProvider(provider))
+		ctx := context.WithValue(context.Background(), "x-amzn-trace-id", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+		_, err := v.buildHandler().Invoke(ctx, []byte(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "5759e988bd862e3fe1be46a994272793", recorder.Ended()[0].SpanContext().TraceID().String())
+	})
+
+	t.Run("panics are recorded", func(t *testing.T) {
+		provider, recorder := newTestTracerProvider()
+		v := NewWithOptions(func() error { panic("boom") }, WithTracerProvider(provider))
+		assert.Panics(t, func() {
+			_, _ = v.buildHandler().Invoke(context.Background(), []byte(`{}`))
+		})
+		assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "panic: boom"}, recorder.Ended()[0].Status())
+	})
+}
@@ -0,0 +1,64 @@
+package vesper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// TypedLambdaFunc is the type-safe form of LambdaFunc, where the input and output
+// types of the handler are known at compile time
+type TypedLambdaFunc[TIn, TOut any] func(context.Context, TIn) (TOut, error)
+
+// TypedMiddleware is the type-safe form of Middleware,
+// take in one TypedLambdaFunc and wrap it within another TypedLambdaFunc
+type TypedMiddleware[TIn, TOut any] func(next TypedLambdaFunc[TIn, TOut]) TypedLambdaFunc[TIn, TOut]
+
+// NewTyped creates a new Vesper instance given a type-safe handler and set of TypedMiddleware.
+// TypedMiddlewares are evaluated in the order they are provided, after any untyped Middleware
+// added with Use, so they always receive the parsed TIn.
+func NewTyped[TIn, TOut any](handler TypedLambdaFunc[TIn, TOut], middlewares ...TypedMiddleware[TIn, TOut]) *Vesper {
+	return New(buildTypedChain(handler, middlewares...))
+}
+
+// buildTypedChain is the type-safe equivalent of buildChain
+func buildTypedChain[TIn, TOut any](f TypedLambdaFunc[TIn, TOut], m ...TypedMiddleware[TIn, TOut]) func(context.Context, TIn) (TOut, error) {
+	if len(m) == 0 {
+		return f
+	}
+	return m[0](buildTypedChain(f, m[1:]...))
+}
+
+// ToMiddleware converts a TypedMiddleware into an untyped Middleware so it can be
+// used in any position of an untyped middleware chain.
+// The input given to the returned Middleware must be assignable to TIn.
+func ToMiddleware[TIn, TOut any](m TypedMiddleware[TIn, TOut]) Middleware {
+	return func(next LambdaFunc) LambdaFunc {
+		typedNext := func(ctx context.Context, in TIn) (TOut, error) {
+			var out TOut
+			res, err := next(ctx, in)
+			if res != nil {
+				var ok bool
+				if out, ok = res.(TOut); !ok {
+					return out, fmt.Errorf("expected response type of %s but got %T from the next middleware", typeName[TOut](), res)
+				}
+			}
+			return out, err
+		}
+		f := m(typedNext)
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			var typedIn TIn
+			if in != nil {
+				var ok bool
+				if typedIn, ok = in.(TIn); !ok {
+					return nil, fmt.Errorf("expected payload type of %s but got %T when calling the typed middleware. parser middlewares probably need to be added", typeName[TIn](), in)
+				}
+			}
+			return f(ctx, typedIn)
+		}
+	}
+}
+
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
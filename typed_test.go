@@ -0,0 +1,93 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTyped(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	t.Run("typed middlewares receive TIn", func(t *testing.T) {
+		var order []string
+		newTypedMiddleware := func(name string) TypedMiddleware[user, string] {
+			return func(next TypedLambdaFunc[user, string]) TypedLambdaFunc[user, string] {
+				return func(ctx context.Context, u user) (string, error) {
+					order = append(order, name)
+					assert.Equal(t, "myuser", u.Name)
+					return next(ctx, u)
+				}
+			}
+		}
+		h := func(ctx context.Context, u user) (string, error) {
+			order = append(order, "handler")
+			return u.Name, nil
+		}
+		v := NewTyped(h, newTypedMiddleware("m1"), newTypedMiddleware("m2"))
+		v.Use(func(next LambdaFunc) LambdaFunc {
+			return func(ctx context.Context, in interface{}) (interface{}, error) {
+				order = append(order, "untyped")
+				return next(ctx, in)
+			}
+		})
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(`{"name": "myuser"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(`"myuser"`), rsp)
+		assert.Equal(t, []string{"untyped", "m1", "m2", "handler"}, order)
+	})
+
+	t.Run("typed middleware short circuits", func(t *testing.T) {
+		h := func(ctx context.Context, u user) (string, error) {
+			t.Error("handler should not have been called")
+			return "", nil
+		}
+		m := func(next TypedLambdaFunc[user, string]) TypedLambdaFunc[user, string] {
+			return func(ctx context.Context, u user) (string, error) {
+				return "", errors.New("unauthorised")
+			}
+		}
+		_, err := NewTyped(h, m).buildHandler().Invoke(context.Background(), []byte(`{}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestToMiddleware(t *testing.T) {
+	m := ToMiddleware(func(next TypedLambdaFunc[int, int]) TypedLambdaFunc[int, int] {
+		return func(ctx context.Context, in int) (int, error) {
+			out, err := next(ctx, in+1)
+			return out * 2, err
+		}
+	})
+
+	t.Run("converts input and output", func(t *testing.T) {
+		f := m(func(ctx context.Context, in interface{}) (interface{}, error) {
+			assert.Equal(t, 2, in)
+			return in, nil
+		})
+		rsp, err := f(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, rsp)
+	})
+
+	t.Run("incompatible input", func(t *testing.T) {
+		f := m(func(ctx context.Context, in interface{}) (interface{}, error) {
+			t.Error("next should not have been called")
+			return nil, nil
+		})
+		_, err := f(context.Background(), "not an int")
+		assert.Error(t, err)
+	})
+
+	t.Run("incompatible output", func(t *testing.T) {
+		f := m(func(ctx context.Context, in interface{}) (interface{}, error) {
+			return "not an int", nil
+		})
+		_, err := f(context.Background(), 1)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,350 @@
+package vesper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationRuleFunc reports whether a value is valid against a validation rule.
+// param is the value given to the rule in the struct tag, e.g. "3" for min=3, and is empty if none was given.
+type ValidationRuleFunc func(value reflect.Value, param string) bool
+
+// ValidatorOption configures the validator of a ValidatorMiddleware
+type ValidatorOption func(rules map[string]ValidationRuleFunc)
+
+// WithValidationRule adds a custom rule which can be used in validate struct tags, replacing any custom rule
+// with the same name, e.g.
+//
+//	vesper.ValidatorMiddleware(vesper.WithValidationRule("even", func(v reflect.Value, param string) bool {
+//		return v.Int()%2 == 0
+//	}))
+func WithValidationRule(name string, rule ValidationRuleFunc) ValidatorOption {
+	return func(rules map[string]ValidationRuleFunc) {
+		rules[name] = rule
+	}
+}
+
+// ValidationError describes a field which failed a validation rule
+type ValidationError struct {
+	// Field is the path of the field, using JSON names, e.g. "address.postcode" or "[0].tags[1]"
+	Field string `json:"field"`
+	// Rule is the name of the rule which failed, e.g. "min"
+	Rule string `json:"rule"`
+	// Param is the parameter of the rule, e.g. "3" for min=3
+	Param string `json:"param,omitempty"`
+	// Message describes the failure
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// ValidationErrors is every failure found when validating a value with ValidatorMiddleware
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidatorMiddleware is a middleware which validates the parsed handler input using `validate` struct tags.
+// It must run after a parser, such as JSONParserMiddleware or JSONSQSParserMiddleware, which is the case for
+// middleware given to New when auto unmarshalling is enabled. Slices and maps are validated element by element,
+// so each record parsed by SQSParserMiddleware is validated.
+//
+// Rules are separated by commas, e.g. `validate:"required,min=3,max=20"`. Nested structs are always validated,
+// and the "dive" rule applies the rules following it to each element of a slice or map field instead of the field itself.
+// The built-in rules are:
+//
+//	required     the value is not the zero value, or empty for slices and maps
+//	omitempty    skip the remaining rules if the value is the zero value
+//	min, max     the minimum or maximum length of a string (in characters), slice or map, or value of a number
+//	len          the exact length of a string, slice or map, or value of a number
+//	gt, lt       like min and max, but exclusive
+//	oneof        the value is one of a space separated list, e.g. oneof=admin user
+//	email, url, uuid, alpha, alphanum, numeric
+//
+// Custom rules are added with WithValidationRule, and only apply to this middleware. If the input is invalid an
+// *HTTPError with a 400 status code is returned, with the ValidationErrors listing every failing field as its Details.
+func ValidatorMiddleware(options ...ValidatorOption) func(LambdaFunc) LambdaFunc {
+	rules := map[string]ValidationRuleFunc{}
+	for _, o := range options {
+		o(rules)
+	}
+	return NamedMiddleware("ValidatorMiddleware", func(next LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			if _, ok := in.([]byte); ok {
+				return nil, fmt.Errorf("validator middleware expected a parsed input but got []byte, it must run after a parser middleware")
+			}
+			errs, err := validateStruct(in, rules)
+			if err != nil {
+				return nil, err
+			}
+			if len(errs) > 0 {
+				middlewareLogger(ctx, "ValidatorMiddleware").Info("invalid input", "error", errs)
+				return nil, &HTTPError{StatusCode: http.StatusBadRequest, Message: "input failed validation", Details: errs, Err: errs}
+			}
+			return next(ctx, in)
+		}
+	})
+}
+
+// validateStruct validates the value using its validate struct tags and the built-in and custom rules,
+// returning an error if a tag is invalid
+func validateStruct(v interface{}, rules map[string]ValidationRuleFunc) (ValidationErrors, error) {
+	vr := &structValidator{rules: rules}
+	if v != nil {
+		vr.traverse(reflect.ValueOf(v), "")
+	}
+	return vr.errs, vr.err
+}
+
+type structValidator struct {
+	rules map[string]ValidationRuleFunc
+	errs  ValidationErrors
+	err   error
+}
+
+// traverse validates the fields of structs found within v
+func (vr *structValidator) traverse(v reflect.Value, path string) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField() && vr.err == nil; i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				continue // unexported
+			}
+			fieldPath := path
+			if !f.Anonymous {
+				fieldPath = joinFieldPath(path, fieldName(f))
+			}
+			if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+				vr.validateField(v.Field(i), fieldPath, strings.Split(tag, ","))
+			}
+			if f.Tag.Get("validate") != "-" {
+				vr.traverse(v.Field(i), fieldPath)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			vr.traverse(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		for _, k := range sortedMapKeys(v) {
+			vr.traverse(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k.Interface()))
+		}
+	}
+}
+
+// validateField applies rules to the value of a field, stopping at the first failing rule
+func (vr *structValidator) validateField(v reflect.Value, path string, rules []string) {
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+			continue
+		case "required":
+			if isEmptyValue(v) {
+				vr.fail(path, name, param, "is required")
+				return
+			}
+			continue
+		case "omitempty":
+			if isEmptyValue(v) {
+				return
+			}
+			continue
+		}
+
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return // only required applies to nil values
+			}
+			v = v.Elem()
+		}
+		if name == "dive" {
+			vr.dive(v, path, rules[i+1:])
+			return
+		}
+
+		ok, msg, err := vr.apply(v, name, param)
+		if err != nil {
+			vr.err = fmt.Errorf("invalid validate tag for field '%s': %w", path, err)
+			return
+		}
+		if !ok {
+			vr.fail(path, name, param, msg)
+			return
+		}
+	}
+}
+
+// dive applies the rules to each element of a slice or map
+func (vr *structValidator) dive(v reflect.Value, path string, rules []string) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			vr.validateField(v.Index(i), fmt.Sprintf("%s[%d]", path, i), rules)
+		}
+	case reflect.Map:
+		for _, k := range sortedMapKeys(v) {
+			vr.validateField(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k.Interface()), rules)
+		}
+	default:
+		vr.err = fmt.Errorf("invalid validate tag for field '%s': dive can only be used on slices and maps", path)
+	}
+}
+
+func (vr *structValidator) fail(path, rule, param, msg string) {
+	vr.errs = append(vr.errs, ValidationError{Field: path, Rule: rule, Param: param, Message: msg})
+}
+
+var (
+	uuidRegexp     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	alphaRegexp    = regexp.MustCompile(`^[a-zA-Z]+$`)
+	alphanumRegexp = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	numericRegexp  = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+)
+
+// apply applies a built-in or custom rule, returning whether the value is valid and a message if not
+func (vr *structValidator) apply(v reflect.Value, name, param string) (bool, string, error) {
+	switch name {
+	case "min", "max", "len", "gt", "gte", "lt", "lte":
+		return compareRule(v, name, param)
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, o := range strings.Fields(param) {
+			if s == o {
+				return true, "", nil
+			}
+		}
+		return false, fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(param), ", ")), nil
+	case "email", "url", "uuid", "alpha", "alphanum", "numeric":
+		if v.Kind() != reflect.String {
+			return false, "", fmt.Errorf("rule '%s' can only be used on strings", name)
+		}
+		return stringRule(v.String(), name), fmt.Sprintf("must be a valid %s", name), nil
+	}
+
+	rule, ok := vr.rules[name]
+	if !ok {
+		return false, "", fmt.Errorf("unknown validation rule '%s'", name)
+	}
+	return rule(v, param), fmt.Sprintf("failed validation rule '%s'", name), nil
+}
+
+func stringRule(s string, name string) bool {
+	switch name {
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "url":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	case "uuid":
+		return uuidRegexp.MatchString(s)
+	case "alpha":
+		return alphaRegexp.MatchString(s)
+	case "alphanum":
+		return alphanumRegexp.MatchString(s)
+	case "numeric":
+		return numericRegexp.MatchString(s)
+	}
+	return false
+}
+
+// compareRule compares the length of strings, slices and maps, or the value of numbers, with the rule parameter
+func compareRule(v reflect.Value, name, param string) (bool, string, error) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false, "", fmt.Errorf("rule '%s' requires a numeric parameter but got '%s'", name, param)
+	}
+
+	var n float64
+	var unit string
+	switch v.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return false, "", fmt.Errorf("rule '%s' cannot be used on %s", name, v.Kind())
+	}
+
+	switch name {
+	case "min", "gte":
+		return n >= limit, fmt.Sprintf("must be at least %s%s", param, unit), nil
+	case "max", "lte":
+		return n <= limit, fmt.Sprintf("must be at most %s%s", param, unit), nil
+	case "len":
+		return n == limit, fmt.Sprintf("must be exactly %s%s", param, unit), nil
+	case "gt":
+		return n > limit, fmt.Sprintf("must be more than %s%s", param, unit), nil
+	default: // lt
+		return n < limit, fmt.Sprintf("must be less than %s%s", param, unit), nil
+	}
+}
+
+// sortedMapKeys returns the keys of a map in a stable order, so failures are always reported in the same order
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Invalid:
+		return true
+	}
+	return v.IsZero()
+}
+
+// fieldName returns the JSON name of a struct field
+func fieldName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
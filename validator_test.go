@@ -0,0 +1,111 @@
+package vesper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validatedAddress struct {
+	Street   string `json:"street" validate:"required"`
+	Postcode string `json:"postcode" validate:"len=4,numeric"`
+}
+
+type validatedUser struct {
+	Email     string                      `json:"email" validate:"required,email"`
+	Name      string                      `json:"name" validate:"required,min=3,max=10"`
+	Age       int                         `json:"age" validate:"gte=18,lt=150"`
+	Role      string                      `json:"role" validate:"omitempty,oneof=admin user"`
+	Website   *string                     `json:"website" validate:"omitempty,url"`
+	Address   *validatedAddress           `json:"address" validate:"required"`
+	Tags      []string                    `json:"tags" validate:"max=3,dive,alphanum"`
+	Addresses map[string]validatedAddress `json:"addresses"`
+	Lucky     int                         `json:"lucky" validate:"omitempty,even"`
+}
+
+var evenRule = WithValidationRule("even", func(v reflect.Value, param string) bool {
+	return v.Int()%2 == 0
+})
+
+func TestValidatorMiddleware(t *testing.T) {
+	valid := `{"email": "bob@example.com", "name": "Bob", "age": 30, "role": "admin", "address": {"street": "Main St", "postcode": "3000"}, "tags": ["a1"], "lucky": 2}`
+	invalid := `{
+		"email": "bob",
+		"name": "Bo",
+		"age": 12,
+		"role": "owner",
+		"website": "example",
+		"tags": ["a", "b!", "c", "d"],
+		"addresses": {"work": {"postcode": "30a0"}, "home": {"street": "Main St", "postcode": "3000"}},
+		"lucky": 7
+	}`
+
+	t.Run("valid", func(t *testing.T) {
+		h := func(ctx context.Context, u validatedUser) (string, error) {
+			return u.Name, nil
+		}
+		v := New(h, ValidatorMiddleware(evenRule))
+		rsp, err := v.buildHandler().Invoke(context.Background(), []byte(valid))
+		assert.NoError(t, err)
+		assert.Equal(t, `"Bob"`, string(rsp))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		h := func(ctx context.Context, u validatedUser) (string, error) {
+			t.Fatal("handler should not be called")
+			return "", nil
+		}
+		v := New(h, ValidatorMiddleware(evenRule))
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(invalid))
+		var httpErr *HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+		assert.Equal(t, ValidationErrors{
+			{Field: "email", Rule: "email", Message: "must be a valid email"},
+			{Field: "name", Rule: "min", Param: "3", Message: "must be at least 3 characters"},
+			{Field: "age", Rule: "gte", Param: "18", Message: "must be at least 18"},
+			{Field: "role", Rule: "oneof", Param: "admin user", Message: "must be one of admin, user"},
+			{Field: "website", Rule: "url", Message: "must be a valid url"},
+			{Field: "address", Rule: "required", Message: "is required"},
+			{Field: "tags", Rule: "max", Param: "3", Message: "must be at most 3 items"},
+			{Field: "addresses[work].street", Rule: "required", Message: "is required"},
+			{Field: "addresses[work].postcode", Rule: "numeric", Message: "must be a valid numeric"},
+			{Field: "lucky", Rule: "even", Message: "failed validation rule 'even'"},
+		}, httpErr.Details)
+	})
+
+	t.Run("dive", func(t *testing.T) {
+		errs, err := validateStruct(validatedUser{Email: "bob@example.com", Name: "Bob", Age: 18, Address: &validatedAddress{Street: "Main St", Postcode: "3000"}, Tags: []string{"a", "b!"}}, nil)
+		assert.NoError(t, err)
+		assert.EqualError(t, errs, "tags[1] must be a valid alphanum")
+	})
+
+	t.Run("records parsed from SQS", func(t *testing.T) {
+		h := func(ctx context.Context, addresses []validatedAddress) error {
+			return nil
+		}
+		v := New(h, JSONSQSParserMiddleware(), ValidatorMiddleware(evenRule)).DisableAutoUnmarshal()
+		_, err := v.buildHandler().Invoke(context.Background(), []byte(`{"Records": [{"body": "{\"street\": \"Main St\", \"postcode\": \"3000\"}"}, {"body": "{\"postcode\": \"3000\"}"}]}`))
+		assert.EqualError(t, err, "400 Bad Request: input failed validation: [1].street is required")
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		type bad struct {
+			Name string `validate:"shiny"`
+		}
+		_, err := validateStruct(bad{}, nil)
+		assert.EqualError(t, err, "invalid validate tag for field 'Name': unknown validation rule 'shiny'")
+	})
+
+	t.Run("custom rules are scoped to the middleware", func(t *testing.T) {
+		h := func(ctx context.Context, u validatedUser) error {
+			return nil
+		}
+		_, err := New(h, ValidatorMiddleware()).buildHandler().Invoke(context.Background(), []byte(valid))
+		assert.EqualError(t, err, "invalid validate tag for field 'lucky': unknown validation rule 'even'")
+	})
+}
@@ -5,17 +5,34 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sync"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mefellows/vesper/encoding"
+	"github.com/mefellows/vesper/metrics"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var log logPrinter = noOpPrinter{}
-
 // Vesper is a middleware adapter for Lambda Functions
 type Vesper struct {
 	rawHandler    interface{}
 	middlewares   []Middleware
 	autoUnmarshal bool
+	unmarshaler   encoding.UnmarshalFunc
+	marshaler     encoding.MarshalFunc
+	recoverPanics bool
+	panicPolicy   PanicPolicy
+	logger        StructuredLogger
+	tracing       bool
+	tracer        trace.TracerProvider
+	propagator    propagation.TextMapPropagator
+	traceChain    bool
+	metrics       *metrics.Emitter
+
+	// built is the handler returned by Handler, which is built on first use
+	built     lambda.Handler
+	buildOnce sync.Once
 }
 
 // New creates a new Vesper instance given a Handler and set of Middleware
@@ -25,10 +42,28 @@ func New(handler interface{}, middlewares ...Middleware) *Vesper {
 		rawHandler:    handler,
 		middlewares:   middlewares,
 		autoUnmarshal: true,
+		unmarshaler:   json.Unmarshal,
+		marshaler:     json.Marshal,
+		logger:        getDefaultLogger(),
 	}
 	return &v
 }
 
+// NewWithOptions creates a new Vesper instance given a Handler and set of Options, e.g.
+//
+//	vesper.NewWithOptions(handler, vesper.WithMiddleware(vesper.WarmupMiddleware), vesper.WithLogger(logger))
+func NewWithOptions(handler interface{}, options ...Option) *Vesper {
+	return New(handler).With(options...)
+}
+
+// With applies options to the instance
+func (v *Vesper) With(options ...Option) *Vesper {
+	for _, o := range options {
+		o(v)
+	}
+	return v
+}
+
 // DisableAutoUnmarshal disables the default behaviour of JSON unmarshaling the payload into the handler input parameter type.
 // This flag should be set if your handler accepts an input parameter other than context.Context but is not directly JSON unmarshalable from the payload.
 //
@@ -51,30 +86,91 @@ func (v *Vesper) Use(middlewares ...Middleware) *Vesper {
 	return v
 }
 
+// UseLogger sets the logger of the instance, which is given to handlers and middleware with the fields of
+// each invocation attached (see LoggerFromContext). By default messages are discarded.
+func (v *Vesper) UseLogger(l StructuredLogger) *Vesper {
+	v.logger = l
+	return v
+}
+
 func (v *Vesper) buildHandler() lambdaHandler {
 	mids := v.middlewares
 	if v.autoUnmarshal {
-		mids = append([]Middleware{JSONParserMiddleware()}, mids...)
+		mids = append([]Middleware{ParserMiddleware(v.unmarshaler)}, mids...)
+	}
+	handler := newTypedToUntypedWrapper(v.rawHandler)
+	if v.metrics != nil || (v.tracing && v.traceChain) {
+		mids = wrapMiddlewares(mids, v.instrumentMiddleware)
+		handler = v.instrumentHandler(handler)
+	}
+	if v.metrics != nil {
+		mids = append([]Middleware{MetricsMiddleware(v.metrics)}, mids...)
+	}
+	if v.tracing {
+		mids = append([]Middleware{TracingMiddleware(v.tracer, v.propagator)}, mids...)
+	}
+	// recover is outermost, so panics in tracing and metrics are recovered as well
+	if v.recoverPanics {
+		mids = append([]Middleware{RecoverMiddleware(v.panicPolicy)}, mids...)
 	}
-	m := buildChain(newTypedToUntypedWrapper(v.rawHandler), mids...)
-	return newMiddlewareWrapper(v.rawHandler, m)
+	m := buildChain(handler, mids...)
+	h := newMiddlewareWrapper(v.rawHandler, m, v.logger)
+	h.marshal = v.marshaler
+	return h
 }
 
-// Start is a convenience function run the lambda handler
-func (v *Vesper) Start() {
-	lambda.StartHandler(v.buildHandler())
+// instrumentMiddleware adds the latency metric and span configured for the instance to a middleware
+func (v *Vesper) instrumentMiddleware(f LambdaFunc) LambdaFunc {
+	if v.metrics != nil {
+		f = measureLambdaFunc("MiddlewareDuration", f)
+	}
+	if v.tracing && v.traceChain {
+		f = traceLambdaFunc("middleware", f)
+	}
+	return f
 }
 
-// Logger sets the log to use
-func Logger(l logPrinter) {
-	log = l
+// instrumentHandler adds the latency metric and span configured for the instance to the handler
+func (v *Vesper) instrumentHandler(f LambdaFunc) LambdaFunc {
+	if v.metrics != nil {
+		f = measureLambdaFunc("HandlerDuration", f)
+	}
+	if v.tracing && v.traceChain {
+		f = traceLambdaFunc("handler", f)
+	}
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		// the handler is not a middleware, even when called by one
+		return f(context.WithValue(ctx, ctxKeyInstrumentedMiddleware, (*instrumentedMiddleware)(nil)), in)
+	}
+}
+
+// Handler builds the middleware chain and handler into a lambda.Handler, which is what Start runs. The handler is
+// built by the first call of Handler or Invoke and reused after, as in a Lambda execution environment, so
+// configuration changes made after the first call are not applied.
+func (v *Vesper) Handler() lambda.Handler {
+	v.buildOnce.Do(func() {
+		v.built = v.buildHandler()
+	})
+	return v.built
+}
+
+// Invoke runs the complete middleware chain and handler with the payload, as the Lambda runtime does, and returns
+// the serialized response. It is useful to test a function without the Lambda runtime, see the vespertest package.
+// It uses the same handler as Handler.
+func (v *Vesper) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	return v.Handler().Invoke(ctx, payload)
+}
+
+// Start is a convenience function run the lambda handler
+func (v *Vesper) Start() {
+	lambda.StartHandler(v.Handler())
 }
 
 // ExtractType fetches the original invocation payload (as a []byte)
 // and converts it to the given narrow type
 // This is useful for situations where a function is invoked from multiple
 // contexts (e.g. warmup, http, S3 events) and handlers/middlewares need to be strongly
-// typed
+// typed. An EventRouter dispatches each source of events to its own typed handler instead.
 func ExtractType(ctx context.Context, in interface{}) error {
 	t := reflect.TypeOf(in)
 
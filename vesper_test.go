@@ -317,3 +317,22 @@ func TestHandler(t *testing.T) {
 		})
 	})
 }
+
+func TestInvoke(t *testing.T) {
+	var coldStarts []bool
+	h := func(ctx context.Context, in string) (string, error) {
+		coldStart, _ := ctx.Value(ctxKeyColdStart).(bool)
+		coldStarts = append(coldStarts, coldStart)
+		return in, nil
+	}
+	v := New(h)
+	for i := 0; i < 2; i++ {
+		rsp, err := v.Invoke(context.Background(), []byte(`"hello"`))
+		assert.NoError(t, err)
+		assert.Equal(t, `"hello"`, string(rsp))
+	}
+	// Handler returns the handler built by Invoke
+	_, err := v.Handler().Invoke(context.Background(), []byte(`"hello"`))
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false, false}, coldStarts)
+}
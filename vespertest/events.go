@@ -0,0 +1,409 @@
+package vespertest
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// The region and account ID of the ARNs of generated events, matching DefaultFunctionARN
+const (
+	Region    = "us-east-1"
+	AccountID = "123456789012"
+)
+
+// The builders below generate events of each trigger type as Lambda would send them, with realistic values for every
+// field the trigger sets. Fields can be overridden on the returned event, e.g.
+//
+//	event := vespertest.SQSEvent(vespertest.SQSMessage(`{"name": "bob"}`))
+//	event.Records[0].Attributes["ApproximateReceiveCount"] = "3"
+
+// APIGatewayProxyRequest generates a request from an API Gateway REST API with the Lambda proxy integration. The path
+// can include a query string, and a body is sent with a JSON content type.
+func APIGatewayProxyRequest(method, path, body string) events.APIGatewayProxyRequest {
+	path, query := splitQuery(path)
+	headers := httpHeaders(body)
+	return events.APIGatewayProxyRequest{
+		Resource:                        path,
+		Path:                            path,
+		HTTPMethod:                      method,
+		Headers:                         headers,
+		MultiValueHeaders:               multiValue(headers),
+		QueryStringParameters:           query,
+		MultiValueQueryStringParameters: multiValue(query),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:    AccountID,
+			ResourceID:   "a1b2c3",
+			Stage:        "prod",
+			RequestID:    newRequestID(),
+			ResourcePath: path,
+			HTTPMethod:   method,
+			APIID:        "1234567890",
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  "203.0.113.10",
+				UserAgent: headers["User-Agent"],
+			},
+		},
+		Body: body,
+	}
+}
+
+// APIGatewayV2HTTPRequest generates a request from an API Gateway HTTP API, with the version 2.0 payload format. The
+// path can include a query string, and a body is sent with a JSON content type.
+func APIGatewayV2HTTPRequest(method, path, body string) events.APIGatewayV2HTTPRequest {
+	path, query := splitQuery(path)
+	now := time.Now()
+	headers := lowerKeys(httpHeaders(body))
+	e := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              "$default",
+		RawPath:               path,
+		RawQueryString:        encodeQuery(query),
+		Headers:               headers,
+		QueryStringParameters: query,
+		Body:                  body,
+	}
+	e.RequestContext.RouteKey = "$default"
+	e.RequestContext.AccountID = AccountID
+	e.RequestContext.Stage = "$default"
+	e.RequestContext.RequestID = newRequestID()
+	e.RequestContext.APIID = "1234567890"
+	e.RequestContext.DomainName = "1234567890.execute-api." + Region + ".amazonaws.com"
+	e.RequestContext.DomainPrefix = "1234567890"
+	e.RequestContext.Time = now.UTC().Format("02/Jan/2006:15:04:05 -0700")
+	e.RequestContext.TimeEpoch = now.UnixMilli()
+	e.RequestContext.HTTP.Method = method
+	e.RequestContext.HTTP.Path = path
+	e.RequestContext.HTTP.Protocol = "HTTP/1.1"
+	e.RequestContext.HTTP.SourceIP = "203.0.113.10"
+	e.RequestContext.HTTP.UserAgent = headers["user-agent"]
+	return e
+}
+
+// ALBTargetGroupRequest generates a request from an Application Load Balancer target group. The path can include a
+// query string, and a body is sent with a JSON content type.
+func ALBTargetGroupRequest(method, path, body string) events.ALBTargetGroupRequest {
+	path, query := splitQuery(path)
+	headers := lowerKeys(httpHeaders(body))
+	headers["x-forwarded-for"] = "203.0.113.10"
+	headers["x-forwarded-port"] = "443"
+	headers["x-forwarded-proto"] = "https"
+	return events.ALBTargetGroupRequest{
+		HTTPMethod:            method,
+		Path:                  path,
+		QueryStringParameters: query,
+		Headers:               headers,
+		RequestContext: events.ALBTargetGroupRequestContext{
+			ELB: events.ELBContext{TargetGroupArn: arn("elasticloadbalancing", "targetgroup/test/0123456789abcdef")},
+		},
+		Body: body,
+	}
+}
+
+// SQSEvent generates an event from an SQS queue with the messages
+func SQSEvent(messages ...events.SQSMessage) events.SQSEvent {
+	return events.SQSEvent{Records: messages}
+}
+
+// SQSMessage generates a message from the "test" SQS queue with the body
+func SQSMessage(body string) events.SQSMessage {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return events.SQSMessage{
+		MessageId:     newRequestID(),
+		ReceiptHandle: base64.StdEncoding.EncodeToString([]byte(newRequestID())),
+		Body:          body,
+		Md5OfBody:     md5Hex(body),
+		Attributes: map[string]string{
+			"ApproximateReceiveCount":          "1",
+			"SentTimestamp":                    now,
+			"SenderId":                         "AIDAIENQZJOLO23YVJ4VO",
+			"ApproximateFirstReceiveTimestamp": now,
+		},
+		MessageAttributes: map[string]events.SQSMessageAttribute{},
+		EventSourceARN:    arn("sqs", "test"),
+		EventSource:       "aws:sqs",
+		AWSRegion:         Region,
+	}
+}
+
+// SNSEvent generates an event from an SNS topic with the records
+func SNSEvent(records ...events.SNSEventRecord) events.SNSEvent {
+	return events.SNSEvent{Records: records}
+}
+
+// SNSRecord generates a notification from the "test" SNS topic with the message
+func SNSRecord(message string) events.SNSEventRecord {
+	topic := arn("sns", "test")
+	return events.SNSEventRecord{
+		EventVersion:         "1.0",
+		EventSubscriptionArn: topic + ":" + newRequestID(),
+		EventSource:          "aws:sns",
+		SNS: events.SNSEntity{
+			Signature:         "EXAMPLE",
+			MessageID:         newRequestID(),
+			Type:              "Notification",
+			TopicArn:          topic,
+			MessageAttributes: map[string]interface{}{},
+			SignatureVersion:  "1",
+			Timestamp:         time.Now().UTC(),
+			SigningCertURL:    "https://sns." + Region + ".amazonaws.com/SimpleNotificationService-0123456789abcdef.pem",
+			Message:           message,
+			UnsubscribeURL:    "https://sns." + Region + ".amazonaws.com/?Action=Unsubscribe&SubscriptionArn=" + topic,
+		},
+	}
+}
+
+// KinesisEvent generates an event from a Kinesis stream with the records
+func KinesisEvent(records ...events.KinesisEventRecord) events.KinesisEvent {
+	return events.KinesisEvent{Records: records}
+}
+
+// KinesisRecord generates a record from the "test" Kinesis stream with the data
+func KinesisRecord(partitionKey string, data []byte) events.KinesisEventRecord {
+	sequenceNumber := newSequenceNumber()
+	return events.KinesisEventRecord{
+		AwsRegion:         Region,
+		EventID:           "shardId-000000000000:" + sequenceNumber,
+		EventName:         "aws:kinesis:record",
+		EventSource:       "aws:kinesis",
+		EventSourceArn:    arn("kinesis", "stream/test"),
+		EventVersion:      "1.0",
+		InvokeIdentityArn: arn("iam", "role/lambda-role"),
+		Kinesis: events.KinesisRecord{
+			ApproximateArrivalTimestamp: events.SecondsEpochTime{Time: time.Now()},
+			Data:                        data,
+			PartitionKey:                partitionKey,
+			SequenceNumber:              sequenceNumber,
+			KinesisSchemaVersion:        "1.0",
+		},
+	}
+}
+
+// DynamoDBEvent generates an event from a DynamoDB stream with the records
+func DynamoDBEvent(records ...events.DynamoDBEventRecord) events.DynamoDBEvent {
+	return events.DynamoDBEvent{Records: records}
+}
+
+// DynamoDBRecord generates a record from the stream of the "test" DynamoDB table, with new and old images. The old
+// image of an INSERT and new image of a REMOVE should be nil.
+func DynamoDBRecord(operation events.DynamoDBOperationType, keys, oldImage, newImage map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+	return events.DynamoDBEventRecord{
+		AWSRegion: Region,
+		Change: events.DynamoDBStreamRecord{
+			ApproximateCreationDateTime: events.SecondsEpochTime{Time: time.Now()},
+			Keys:                        keys,
+			NewImage:                    newImage,
+			OldImage:                    oldImage,
+			SequenceNumber:              newSequenceNumber(),
+			SizeBytes:                   int64(len(mustMarshal(keys)) + len(mustMarshal(oldImage)) + len(mustMarshal(newImage))),
+			StreamViewType:              "NEW_AND_OLD_IMAGES",
+		},
+		EventID:        newRequestID(),
+		EventName:      string(operation),
+		EventSource:    "aws:dynamodb",
+		EventVersion:   "1.1",
+		EventSourceArn: arn("dynamodb", "table/test/stream/2024-01-01T00:00:00.000"),
+	}
+}
+
+// S3Event generates an event from S3 with the records
+func S3Event(records ...events.S3EventRecord) events.S3Event {
+	return events.S3Event{Records: records}
+}
+
+// S3Record generates an ObjectCreated:Put notification for the object in the bucket
+func S3Record(bucket, key string, size int64) events.S3EventRecord {
+	return events.S3EventRecord{
+		EventVersion:      "2.1",
+		EventSource:       "aws:s3",
+		AWSRegion:         Region,
+		EventTime:         time.Now().UTC(),
+		EventName:         "ObjectCreated:Put",
+		PrincipalID:       events.S3UserIdentity{PrincipalID: "AWS:AIDAIENQZJOLO23YVJ4VO"},
+		RequestParameters: events.S3RequestParameters{SourceIPAddress: "203.0.113.10"},
+		ResponseElements: map[string]string{
+			"x-amz-request-id": strings.ToUpper(newSequenceNumber()[:16]),
+			"x-amz-id-2":       base64.StdEncoding.EncodeToString([]byte(newRequestID())),
+		},
+		S3: events.S3Entity{
+			SchemaVersion:   "1.0",
+			ConfigurationID: "test",
+			Bucket: events.S3Bucket{
+				Name:          bucket,
+				OwnerIdentity: events.S3UserIdentity{PrincipalID: "A3NL1KOZZKExample"},
+				Arn:           "arn:aws:s3:::" + bucket,
+			},
+			Object: events.S3Object{
+				Key:       key,
+				Size:      size,
+				ETag:      md5Hex(key),
+				Sequencer: strings.ToUpper(newSequenceNumber()[:18]),
+			},
+		},
+	}
+}
+
+// EventBridgeEvent generates an event from the default EventBridge event bus, with the detail marshaled as JSON. It
+// panics if the detail cannot be marshaled.
+func EventBridgeEvent(source, detailType string, detail interface{}) events.CloudWatchEvent {
+	return events.CloudWatchEvent{
+		Version:    "0",
+		ID:         newRequestID(),
+		DetailType: detailType,
+		Source:     source,
+		AccountID:  AccountID,
+		Time:       time.Now().UTC().Truncate(time.Second),
+		Region:     Region,
+		Resources:  []string{},
+		Detail:     mustMarshal(detail),
+	}
+}
+
+// CognitoPreSignupEvent generates a PreSignUp_SignUp trigger from the "test" Cognito user pool
+func CognitoPreSignupEvent(userName string, attributes map[string]string) events.CognitoEventUserPoolsPreSignup {
+	return events.CognitoEventUserPoolsPreSignup{
+		CognitoEventUserPoolsHeader: cognitoHeader("PreSignUp_SignUp", userName),
+		Request: events.CognitoEventUserPoolsPreSignupRequest{
+			UserAttributes: attributes,
+			ValidationData: map[string]string{},
+			ClientMetadata: map[string]string{},
+		},
+	}
+}
+
+// CognitoPreAuthenticationEvent generates a PreAuthentication_Authentication trigger from the "test" Cognito user
+// pool
+func CognitoPreAuthenticationEvent(userName string, attributes map[string]string) events.CognitoEventUserPoolsPreAuthentication {
+	return events.CognitoEventUserPoolsPreAuthentication{
+		CognitoEventUserPoolsHeader: cognitoHeader("PreAuthentication_Authentication", userName),
+		Request: events.CognitoEventUserPoolsPreAuthenticationRequest{
+			UserAttributes: attributes,
+			ValidationData: map[string]string{},
+		},
+	}
+}
+
+// CognitoPostConfirmationEvent generates a PostConfirmation_ConfirmSignUp trigger from the "test" Cognito user pool
+func CognitoPostConfirmationEvent(userName string, attributes map[string]string) events.CognitoEventUserPoolsPostConfirmation {
+	return events.CognitoEventUserPoolsPostConfirmation{
+		CognitoEventUserPoolsHeader: cognitoHeader("PostConfirmation_ConfirmSignUp", userName),
+		Request: events.CognitoEventUserPoolsPostConfirmationRequest{
+			UserAttributes: attributes,
+			ClientMetadata: map[string]string{},
+		},
+	}
+}
+
+func cognitoHeader(triggerSource, userName string) events.CognitoEventUserPoolsHeader {
+	return events.CognitoEventUserPoolsHeader{
+		Version:       "1",
+		TriggerSource: triggerSource,
+		Region:        Region,
+		UserPoolID:    Region + "_EXAMPLE",
+		CallerContext: events.CognitoEventUserPoolsCallerContext{
+			AWSSDKVersion: "aws-sdk-unknown-unknown",
+			ClientID:      "1example23456789",
+		},
+		UserName: userName,
+	}
+}
+
+// httpHeaders returns the headers of an HTTP request from a client, with a JSON content type if it has a body
+func httpHeaders(body string) map[string]string {
+	headers := map[string]string{
+		"Accept":          "*/*",
+		"Host":            "1234567890.execute-api." + Region + ".amazonaws.com",
+		"User-Agent":      "curl/8.4.0",
+		"X-Amzn-Trace-Id": newTraceHeader(),
+	}
+	if body != "" {
+		headers["Content-Type"] = "application/json"
+		headers["Content-Length"] = strconv.Itoa(len(body))
+	}
+	return headers
+}
+
+// splitQuery splits the query string from a path into its parameters, or nil if it has none
+func splitQuery(path string) (string, map[string]string) {
+	path, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path, nil
+	}
+	query := map[string]string{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		k, v, _ := strings.Cut(pair, "=")
+		query[k] = v
+	}
+	return path, query
+}
+
+func encodeQuery(query map[string]string) string {
+	var pairs []string
+	for k, v := range query {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, "&")
+}
+
+func multiValue(m map[string]string) map[string][]string {
+	if m == nil {
+		return nil
+	}
+	mv := make(map[string][]string, len(m))
+	for k, v := range m {
+		mv[k] = []string{v}
+	}
+	return mv
+}
+
+func lowerKeys(m map[string]string) map[string]string {
+	lower := make(map[string]string, len(m))
+	for k, v := range m {
+		lower[strings.ToLower(k)] = v
+	}
+	return lower
+}
+
+func arn(service, resource string) string {
+	region := Region
+	if service == "iam" {
+		region = "" // IAM is global
+	}
+	return fmt.Sprintf("arn:aws:%s:%s:%s:%s", service, region, AccountID, resource)
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("could not marshal %T: %s", v, err))
+	}
+	return b
+}
+
+// newSequenceNumber generates a random sequence number of a stream record
+func newSequenceNumber() string {
+	id := strings.ReplaceAll(newRequestID(), "-", "")
+	var digits strings.Builder
+	for _, c := range id {
+		digits.WriteString(strconv.Itoa(int(c) % 10))
+	}
+	return digits.String()
+}
+
+// newTraceHeader generates a random X-Ray trace header
+func newTraceHeader() string {
+	id := strings.ReplaceAll(newRequestID(), "-", "")
+	return fmt.Sprintf("Root=1-%08x-%s", time.Now().Unix(), id[:24])
+}
@@ -0,0 +1,132 @@
+package vespertest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPEvents(t *testing.T) {
+	for name, tc := range map[string]struct {
+		event  interface{}
+		source vesper.HTTPEventSource
+	}{
+		"API Gateway":    {APIGatewayProxyRequest("POST", "/users?active=true", `{"name": "bob"}`), vesper.HTTPEventSourceAPIGateway},
+		"API Gateway v2": {APIGatewayV2HTTPRequest("POST", "/users?active=true", `{"name": "bob"}`), vesper.HTTPEventSourceAPIGatewayV2},
+		"ALB":            {ALBTargetGroupRequest("POST", "/users?active=true", `{"name": "bob"}`), vesper.HTTPEventSourceALB},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := func(ctx context.Context, r vesper.HTTPRequest) (vesper.HTTPResponse, error) {
+				assert.Equal(t, tc.source, r.Source)
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "/users", r.Path)
+				assert.Equal(t, map[string]string{"active": "true"}, r.QueryStringParameters)
+				assert.Equal(t, "application/json", r.Header("Content-Type"))
+				assert.Equal(t, `{"name": "bob"}`, r.Body)
+				return vesper.HTTPResponse{StatusCode: 204}, nil
+			}
+			var rsp events.APIGatewayProxyResponse
+			assert.NoError(t, InvokeEvent(vesper.New(h), tc.event, &rsp))
+			assert.Equal(t, 204, rsp.StatusCode)
+		})
+	}
+}
+
+func TestRecordEvents(t *testing.T) {
+	t.Run("SQS", func(t *testing.T) {
+		var bodies []string
+		h := func(ctx context.Context, u user) error {
+			msg, ok := vesper.SQSMessageFromContext(ctx)
+			assert.True(t, ok)
+			bodies = append(bodies, msg.Body)
+			assert.Equal(t, "arn:aws:sqs:us-east-1:123456789012:test", msg.EventSourceARN)
+			return nil
+		}
+		event := SQSEvent(SQSMessage(`{"name": "bob"}`), SQSMessage(`{"name": "alice"}`))
+		assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", SQSMessage("").Md5OfBody)
+		assert.NotEqual(t, event.Records[0].MessageId, event.Records[1].MessageId)
+		v := vesper.New(h, vesper.JSONSQSRecordHandlerMiddleware()).DisableAutoUnmarshal()
+		assert.NoError(t, InvokeEvent(v, event, nil))
+		assert.Equal(t, []string{`{"name": "bob"}`, `{"name": "alice"}`}, bodies)
+	})
+
+	t.Run("SNS", func(t *testing.T) {
+		var users []user
+		h := func(ctx context.Context, in []user) error {
+			users = in
+			return nil
+		}
+		v := vesper.New(h, vesper.JSONSNSParserMiddleware()).DisableAutoUnmarshal()
+		assert.NoError(t, InvokeEvent(v, SNSEvent(SNSRecord(`{"name": "bob"}`)), nil))
+		assert.Equal(t, []user{{Name: "bob"}}, users)
+	})
+
+	t.Run("Kinesis", func(t *testing.T) {
+		var keys []string
+		h := func(ctx context.Context, u user) error {
+			record, _ := vesper.KinesisRecordFromContext(ctx)
+			keys = append(keys, record.Kinesis.PartitionKey)
+			assert.Equal(t, "bob", u.Name)
+			return nil
+		}
+		v := vesper.New(h, vesper.JSONKinesisRecordHandlerMiddleware()).DisableAutoUnmarshal()
+		assert.NoError(t, InvokeEvent(v, KinesisEvent(KinesisRecord("users", []byte(`{"name": "bob"}`))), nil))
+		assert.Equal(t, []string{"users"}, keys)
+	})
+
+	t.Run("DynamoDB", func(t *testing.T) {
+		var names []string
+		h := func(ctx context.Context, r events.DynamoDBEventRecord) error {
+			names = append(names, r.EventName)
+			return nil
+		}
+		keys := map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("1")}
+		image := map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("1"), "name": events.NewStringAttribute("bob")}
+		event := DynamoDBEvent(
+			DynamoDBRecord(events.DynamoDBOperationTypeInsert, keys, nil, image),
+			DynamoDBRecord(events.DynamoDBOperationTypeRemove, keys, image, nil),
+		)
+		v := vesper.New(h, vesper.DynamoDBRecordHandlerMiddleware()).DisableAutoUnmarshal()
+		assert.NoError(t, InvokeEvent(v, event, nil))
+		assert.Equal(t, []string{"INSERT", "REMOVE"}, names)
+	})
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("S3", func(t *testing.T) {
+		e := S3Event(S3Record("uploads", "users/bob.json", 128))
+		assert.Equal(t, "arn:aws:s3:::uploads", e.Records[0].S3.Bucket.Arn)
+		assert.Equal(t, "users/bob.json", e.Records[0].S3.Object.Key)
+		assert.Equal(t, "ObjectCreated:Put", e.Records[0].EventName)
+	})
+
+	t.Run("EventBridge", func(t *testing.T) {
+		var detail user
+		h := func(ctx context.Context, e events.CloudWatchEvent) error {
+			assert.Equal(t, "users", e.Source)
+			assert.Equal(t, "UserCreated", e.DetailType)
+			return json.Unmarshal(e.Detail, &detail)
+		}
+		assert.NoError(t, InvokeEvent(vesper.New(h), EventBridgeEvent("users", "UserCreated", user{Name: "bob"}), nil))
+		assert.Equal(t, user{Name: "bob"}, detail)
+		assert.Panics(t, func() { EventBridgeEvent("users", "UserCreated", make(chan int)) })
+	})
+
+	t.Run("Cognito", func(t *testing.T) {
+		h := func(ctx context.Context, e events.CognitoEventUserPoolsPreSignup) (events.CognitoEventUserPoolsPreSignup, error) {
+			e.Response.AutoConfirmUser = strings.HasSuffix(e.Request.UserAttributes["email"], "@example.com")
+			return e, nil
+		}
+		var rsp events.CognitoEventUserPoolsPreSignup
+		assert.NoError(t, InvokeEvent(vesper.New(h), CognitoPreSignupEvent("bob", map[string]string{"email": "bob@example.com"}), &rsp))
+		assert.True(t, rsp.Response.AutoConfirmUser)
+		assert.Equal(t, "PreSignUp_SignUp", rsp.TriggerSource)
+		assert.Equal(t, "PreAuthentication_Authentication", CognitoPreAuthenticationEvent("bob", nil).TriggerSource)
+		assert.Equal(t, "PostConfirmation_ConfirmSignUp", CognitoPostConfirmationEvent("bob", nil).TriggerSource)
+	})
+}
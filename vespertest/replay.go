@@ -0,0 +1,74 @@
+package vespertest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// ReplayResult is the result of invoking a handler with an event being replayed
+type ReplayResult struct {
+	// Line is the line number of the event in the replayed input
+	Line     int
+	Event    json.RawMessage
+	Response []byte
+	Err      error
+	Duration time.Duration
+}
+
+// Replay invokes the handler with each event of a JSONL input, e.g. events recorded from a deployed function, in
+// order. Blank lines are skipped, and an error is returned if a line is not valid JSON, before any are invoked.
+// Each invocation has a new context created with the options.
+func Replay(h lambda.Handler, r io.Reader, options ...Option) ([]ReplayResult, error) {
+	var results []ReplayResult
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("could not read line %d: %w", line, err)
+		}
+		if event := bytes.TrimSpace(b); len(event) > 0 {
+			if !json.Valid(event) {
+				return nil, fmt.Errorf("could not read line %d: invalid JSON", line)
+			}
+			results = append(results, ReplayResult{Line: line, Event: event})
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	for i := range results {
+		start := time.Now()
+		results[i].Response, results[i].Err = Invoke(h, results[i].Event, options...)
+		results[i].Duration = time.Since(start)
+	}
+	return results, nil
+}
+
+// WriteReport writes a line for each result with its duration and response or error, followed by a summary
+func WriteReport(w io.Writer, results []ReplayResult) error {
+	var failed int
+	var total time.Duration
+	for _, r := range results {
+		total += r.Duration
+		outcome := "ok " + string(r.Response)
+		if r.Err != nil {
+			failed++
+			outcome = "error " + r.Err.Error()
+		}
+		if _, err := fmt.Fprintf(w, "line %d: %s %s\n", r.Line, r.Duration.Round(time.Microsecond), outcome); err != nil {
+			return fmt.Errorf("could not write report: %w", err)
+		}
+	}
+	if _, err := fmt.Fprintf(w, "%d events, %d failed, %s\n", len(results), failed, total.Round(time.Microsecond)); err != nil {
+		return fmt.Errorf("could not write report: %w", err)
+	}
+	return nil
+}
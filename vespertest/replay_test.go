@@ -0,0 +1,43 @@
+package vespertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mefellows/vesper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplay(t *testing.T) {
+	h := func(ctx context.Context, u user) (string, error) {
+		if u.Name == "" {
+			return "", errors.New("missing name")
+		}
+		return "hello " + u.Name, nil
+	}
+	input := `{"name": "bob"}
+
+{"name": ""}
+{"name": "alice"}`
+	results, err := Replay(vesper.New(h), strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, []int{1, 3, 4}, []int{results[0].Line, results[1].Line, results[2].Line})
+	assert.Equal(t, `"hello bob"`, string(results[0].Response))
+	assert.EqualError(t, results[1].Err, "missing name")
+	assert.Equal(t, `"hello alice"`, string(results[2].Response))
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteReport(&buf, results))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Regexp(t, `^line 1: \S+ ok "hello bob"$`, lines[0])
+	assert.Regexp(t, `^line 3: \S+ error missing name$`, lines[1])
+	assert.Regexp(t, `^3 events, 1 failed, \S+$`, lines[3])
+
+	_, err = Replay(vesper.New(h), strings.NewReader("{\"name\": \"bob\"}\n{\n"))
+	assert.EqualError(t, err, "could not read line 2: invalid JSON")
+}
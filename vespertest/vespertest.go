@@ -0,0 +1,145 @@
+// Package vespertest provides utilities for testing Vesper functions, invoking the complete middleware chain and
+// handler as the Lambda runtime would, without running in Lambda.
+//
+//	v := vesper.New(MyHandler, vesper.CorrelationIDMiddleware())
+//	rsp, err := vespertest.Invoke(v, []byte(`{"name": "bob"}`), vespertest.WithTimeout(time.Second))
+//
+// It also generates realistic events for each trigger type, e.g. vespertest.SQSEvent, and replays recorded events
+// from a JSONL file with Replay.
+package vespertest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/mefellows/vesper"
+)
+
+const (
+	// DefaultFunctionARN is the ARN of the function being invoked, unless set with WithFunctionARN
+	DefaultFunctionARN = "arn:aws:lambda:us-east-1:123456789012:function:test"
+	// DefaultTimeout is the timeout of an invocation unless set with WithTimeout, which is the Lambda default
+	DefaultTimeout = 3 * time.Second
+)
+
+type invocation struct {
+	requestID     string
+	functionARN   string
+	timeout       time.Duration
+	traceHeader   string
+	identity      lambdacontext.CognitoIdentity
+	clientContext lambdacontext.ClientContext
+}
+
+// Option configures the Lambda context of an invocation
+type Option func(*invocation)
+
+// WithRequestID sets the request ID of the invocation, which is otherwise random
+func WithRequestID(id string) Option {
+	return func(i *invocation) {
+		i.requestID = id
+	}
+}
+
+// WithFunctionARN sets the ARN the function was invoked with
+func WithFunctionARN(arn string) Option {
+	return func(i *invocation) {
+		i.functionARN = arn
+	}
+}
+
+// WithTimeout sets the timeout of the function, from which the deadline of the invocation is set
+func WithTimeout(timeout time.Duration) Option {
+	return func(i *invocation) {
+		i.timeout = timeout
+	}
+}
+
+// WithTraceHeader sets the X-Ray trace header given to the function by Lambda, e.g.
+// Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1
+func WithTraceHeader(header string) Option {
+	return func(i *invocation) {
+		i.traceHeader = header
+	}
+}
+
+// WithIdentity sets the Cognito identity of the caller
+func WithIdentity(identity lambdacontext.CognitoIdentity) Option {
+	return func(i *invocation) {
+		i.identity = identity
+	}
+}
+
+// WithClientContext sets the client context given by the calling application
+func WithClientContext(clientContext lambdacontext.ClientContext) Option {
+	return func(i *invocation) {
+		i.clientContext = clientContext
+	}
+}
+
+// NewContext creates the context of an invocation as the Lambda runtime does, with a LambdaContext and a deadline
+// of the timeout from now. The returned cancel function must be called to release its resources.
+func NewContext(parent context.Context, options ...Option) (context.Context, context.CancelFunc) {
+	i := invocation{requestID: newRequestID(), functionARN: DefaultFunctionARN, timeout: DefaultTimeout}
+	for _, o := range options {
+		o(&i)
+	}
+	ctx, cancel := context.WithTimeout(parent, i.timeout)
+	ctx = lambdacontext.NewContext(ctx, &lambdacontext.LambdaContext{
+		AwsRequestID:       i.requestID,
+		InvokedFunctionArn: i.functionARN,
+		Identity:           i.identity,
+		ClientContext:      i.clientContext,
+	})
+	if i.traceHeader != "" {
+		ctx = context.WithValue(ctx, "x-amzn-trace-id", i.traceHeader)
+	}
+	return ctx, cancel
+}
+
+// Invoke invokes the handler with the payload as the Lambda runtime does, and returns the serialized response.
+// The handler is usually a *vesper.Vesper, but can be any lambda.Handler. A panic is returned as a
+// *vesper.PanicError, as the Lambda runtime reports it as the invocation error.
+func Invoke(h lambda.Handler, payload []byte, options ...Option) (rsp []byte, err error) {
+	ctx, cancel := NewContext(context.Background(), options...)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			rsp, err = nil, &vesper.PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return h.Invoke(ctx, payload)
+}
+
+// InvokeEvent marshals the event as JSON and invokes the handler with it, e.g. with an events.SQSEvent. If response
+// is not nil, the response is unmarshaled into it as JSON.
+func InvokeEvent(h lambda.Handler, event interface{}, response interface{}, options ...Option) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not marshal event: %w", err)
+	}
+	rsp, err := Invoke(h, payload, options...)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return nil
+	}
+	if err := json.Unmarshal(rsp, response); err != nil {
+		return fmt.Errorf("could not unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// newRequestID generates a random request ID in the format used by Lambda
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
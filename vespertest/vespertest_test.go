@@ -0,0 +1,94 @@
+package vespertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/mefellows/vesper"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func TestInvoke(t *testing.T) {
+	t.Run("lambda context", func(t *testing.T) {
+		h := func(ctx context.Context, u user) (string, error) {
+			lc, ok := lambdacontext.FromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "request-1", lc.AwsRequestID)
+			assert.Equal(t, "arn:aws:lambda:eu-west-1:123456789012:function:users", lc.InvokedFunctionArn)
+			assert.Equal(t, "app", lc.ClientContext.Client.AppTitle)
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+			assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1", ctx.Value("x-amzn-trace-id"))
+			return "hello " + u.Name, nil
+		}
+		rsp, err := Invoke(vesper.New(h), []byte(`{"name": "bob"}`),
+			WithRequestID("request-1"),
+			WithFunctionARN("arn:aws:lambda:eu-west-1:123456789012:function:users"),
+			WithTimeout(time.Second),
+			WithTraceHeader("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"),
+			WithClientContext(lambdacontext.ClientContext{Client: lambdacontext.ClientApplication{AppTitle: "app"}}),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, `"hello bob"`, string(rsp))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		var requestIDs []string
+		h := func(ctx context.Context) error {
+			lc, _ := lambdacontext.FromContext(ctx)
+			assert.Equal(t, DefaultFunctionARN, lc.InvokedFunctionArn)
+			requestIDs = append(requestIDs, lc.AwsRequestID)
+			deadline, _ := ctx.Deadline()
+			assert.WithinDuration(t, time.Now().Add(DefaultTimeout), deadline, 100*time.Millisecond)
+			return nil
+		}
+		v := vesper.New(h)
+		for i := 0; i < 2; i++ {
+			_, err := Invoke(v, []byte(`{}`))
+			assert.NoError(t, err)
+		}
+		assert.Len(t, requestIDs, 2)
+		assert.NotEqual(t, requestIDs[0], requestIDs[1])
+	})
+
+	t.Run("complete middleware stack", func(t *testing.T) {
+		h := func(ctx context.Context, u user) error {
+			if u.Name == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		}
+		v := vesper.New(h, vesper.TimeoutMiddleware(100*time.Millisecond), vesper.JSONSQSRecordHandlerMiddleware(), vesper.ValidatorMiddleware()).DisableAutoUnmarshal()
+		event := events.SQSEvent{Records: []events.SQSMessage{
+			{MessageId: "1", Body: `{"name": "bob"}`},
+			{MessageId: "2", Body: `{"name": "fail"}`},
+			{MessageId: "3", Body: `{}`},
+		}}
+		var rsp vesper.BatchResponse
+		assert.NoError(t, InvokeEvent(v, event, &rsp))
+		assert.Equal(t, []vesper.BatchItemFailure{{ItemIdentifier: "2"}, {ItemIdentifier: "3"}}, rsp.BatchItemFailures)
+	})
+
+	t.Run("panics are returned as errors", func(t *testing.T) {
+		v := vesper.New(func() error { panic("boom") })
+		_, err := Invoke(v, []byte(`{}`))
+		var panicErr *vesper.PanicError
+		assert.True(t, errors.As(err, &panicErr))
+		assert.Equal(t, "boom", panicErr.Value)
+	})
+
+	t.Run("invalid event", func(t *testing.T) {
+		err := InvokeEvent(vesper.New(func() error { return nil }), make(chan int), nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "could not marshal event")
+	})
+}
@@ -21,20 +21,23 @@ type warmupEvent struct {
 // plugin "serverless-plugin-warmup", and returns early if found
 //
 // See https://www.npmjs.com/package/serverless-plugin-warmup for more
-func WarmupMiddleware(f LambdaFunc) LambdaFunc {
-	return func(ctx context.Context, in interface{}) (interface{}, error) {
-		log.Println("[warmupMiddleware] START")
-		var event warmupEvent
-		if err := ExtractType(ctx, &event); err == nil {
-			if event.Event.Source == "serverless-plugin-warmup" {
-				log.Println("[warmupMiddleware] warmup event detected, exiting")
-				return "warmup", nil
+func WarmupMiddleware(next LambdaFunc) LambdaFunc {
+	return NamedMiddleware("WarmupMiddleware", func(f LambdaFunc) LambdaFunc {
+		return func(ctx context.Context, in interface{}) (interface{}, error) {
+			logger := middlewareLogger(ctx, "WarmupMiddleware")
+			logger.Debug("START")
+			var event warmupEvent
+			if err := ExtractType(ctx, &event); err == nil {
+				if event.Event.Source == "serverless-plugin-warmup" {
+					logger.Info("warmup event detected, exiting")
+					return "warmup", nil
+				}
 			}
-		}
 
-		res, err := f(ctx, in)
-		log.Println("[warmupMiddleware] END: ", res)
+			res, err := f(ctx, in)
+			logger.Debug("END", "response", res)
 
-		return res, err
-	}
+			return res, err
+		}
+	})(next)
 }